@@ -0,0 +1,163 @@
+// This file contains the logic for type-checking a package's source files.
+package pkg
+
+import (
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// sharedImporter is used to import the dependencies of packages that are not loaded with a Config.
+// It finds the export data of the standard library's packages relative to the current working
+// directory, so the types of declarations that come from any other package are left unresolved.
+// Sharing one importer means that each dependency only has to be read once no matter how many
+// packages are loaded.
+var sharedImporter = &lockedImporter{importer: importer.Default()}
+
+// lockedImporter is a types.ImporterFrom that serializes access to an underlying importer, which
+// is not safe for concurrent use on its own.
+type lockedImporter struct {
+	mu       sync.Mutex
+	importer types.Importer
+}
+
+// typeInfo holds the results of type-checking a package. A nil *typeInfo is valid and means that no
+// type information is available.
+type typeInfo struct {
+	// Type-checked package.
+	pkg *types.Package
+
+	// Type information for the package's syntax trees.
+	info *types.Info
+}
+
+// typeCheck type-checks the files that are part of the package's build for this system and returns
+// the resulting type information. Type checking is best-effort: errors (like missing dependencies) are
+// ignored, and whatever information could be determined is returned. This must be called before the
+// files are handed to go/doc, which removes unexported declarations from the syntax trees. Dependencies
+// are imported with imp, or with sharedImporter if imp is nil.
+func typeCheck(buildPkg *build.Package, astPkg *ast.Package, fset *token.FileSet, importPath string, imp types.Importer) *typeInfo {
+	if buildPkg == nil || astPkg == nil {
+		return nil
+	}
+
+	// Only the files for this system's build can be checked together. Files for other systems would
+	// cause redeclaration errors.
+	inBuild := make(map[string]bool)
+	for _, ss := range [][]string{buildPkg.GoFiles, buildPkg.CgoFiles} {
+		for _, s := range ss {
+			inBuild[s] = true
+		}
+	}
+
+	names := make([]string, 0, len(astPkg.Files))
+	for name := range astPkg.Files {
+		if inBuild[filepath.Base(name)] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	files := make([]*ast.File, len(names))
+	for i, name := range names {
+		files[i] = astPkg.Files[name]
+	}
+
+	if importPath == "" {
+		importPath = buildPkg.Name
+	}
+
+	if imp == nil {
+		imp = sharedImporter
+	}
+	config := types.Config{
+		Importer:    imp,
+		FakeImportC: true,
+		Error:       func(error) {},
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	pkg, _ := config.Check(importPath, fset, files, info)
+	if pkg == nil {
+		return nil
+	}
+
+	return &typeInfo{pkg: pkg, info: info}
+}
+
+// Import imports the package at path.
+func (li *lockedImporter) Import(path string) (*types.Package, error) {
+	return li.ImportFrom(path, "", 0)
+}
+
+// ImportFrom imports the package at path, resolving vendored packages relative to dir.
+func (li *lockedImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	if from, ok := li.importer.(types.ImporterFrom); ok {
+		return from.ImportFrom(path, dir, mode)
+	}
+
+	return li.importer.Import(path)
+}
+
+// lookup returns the package-level object with the given name, or nil if there isn't one or no type
+// information is available.
+func (ti *typeInfo) lookup(name string) types.Object {
+	if ti == nil {
+		return nil
+	}
+
+	return ti.pkg.Scope().Lookup(name)
+}
+
+// qualifier returns the qualifier used to print types: types from this package are printed without a
+// package name, and types from other packages are printed with their package's name.
+func (ti *typeInfo) qualifier(p *types.Package) string {
+	if ti != nil && p == ti.pkg {
+		return ""
+	}
+
+	return p.Name()
+}
+
+// typeString returns the string representation of typ as it would be written in this package.
+func (ti *typeInfo) typeString(typ types.Type) string {
+	return types.TypeString(typ, ti.qualifier)
+}
+
+// newTupleParameters builds a list of parameters from a tuple of a function's inputs or outputs. If
+// variadic is true, the last parameter is printed as a variadic parameter.
+func (ti *typeInfo) newTupleParameters(tuple *types.Tuple, variadic bool) []Parameter {
+	params := make([]Parameter, 0, tuple.Len())
+	for i := 0; i < tuple.Len(); i++ {
+		v := tuple.At(i)
+
+		typeName := ti.typeString(v.Type())
+		if variadic && i == tuple.Len()-1 {
+			if s, ok := v.Type().(*types.Slice); ok {
+				typeName = "..." + ti.typeString(s.Elem())
+			}
+		}
+
+		params = append(params, Parameter{
+			name:     strings.TrimSpace(v.Name()),
+			typeName: typeName,
+		})
+	}
+
+	return params
+}
@@ -0,0 +1,425 @@
+// Pkgdoc prints the documentation for one or more Go packages, or for a single symbol in a package.
+//
+// Usage:
+//
+//	pkgdoc [flags] package...
+//	pkgdoc [flags] package symbol
+//
+// Each package is an import path or a directory (like "./internal/foo"), resolved in module mode the
+// same way as the go command. A symbol is a constant, variable, function, or type (like "Dial"), or a
+// method or field of a type (like "Client.Call"), and is resolved the same way as go doc resolves it.
+// The second of exactly two arguments is taken as a symbol if it is a name (exported or not) or has the
+// form "Type.Method", unless a list is requested. A lower-case name that is not a symbol in the first
+// package but is the import path of another package is taken as a package instead, like "errors" in
+// "pkgdoc io errors".
+//
+// With -tags, only the files and declarations built for this system with the given build tags are
+// documented.
+//
+// With -functions, -types, -errors, or -imports, only that list is printed for each package. The
+// output is plain text in the layout of go doc, JSON in the layout of the pkg module's schema, or
+// Markdown, depending on -format.
+//
+// Pkgdoc exits with one of these codes:
+//
+//	0  success
+//	1  the output could not be written
+//	2  the flags or arguments are invalid
+//	3  a package or symbol was not found
+//	4  a package was found but could not be built or parsed
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/snhilde/pkg"
+)
+
+// Exit codes.
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitInvalid  = 4
+)
+
+// usage is printed above the flags' defaults.
+const usage = `usage: pkgdoc [flags] package...
+       pkgdoc [flags] package symbol
+
+flags:
+`
+
+// symbolPattern matches the name of a symbol, like "Dial" or "Client.Call".
+var symbolPattern = regexp.MustCompile(`^[\pL_][\pL\pN_]*(\.[\pL_][\pL\pN_]*)?$`)
+
+// options holds the command-line options that control the output.
+type options struct {
+	// Output format: "text", "json", or "markdown".
+	format string
+
+	// List to print instead of the full documentation: "functions", "types", "errors", "imports", or
+	// "" for none.
+	list string
+
+	// Whether or not to print the full documentation for everything.
+	all bool
+
+	// Whether or not to print only a one-line summary of each item.
+	short bool
+
+	// Width at which comments are wrapped in text output.
+	width int
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command with the given arguments and returns the exit code.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("pkgdoc", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	var opts options
+	var cfg pkg.Config
+	flags.StringVar(&opts.format, "format", "text", "output `format`: text, json, or markdown")
+	flags.BoolVar(&opts.all, "all", false, "print the full documentation for everything")
+	flags.BoolVar(&opts.short, "short", false, "print only a one-line summary of each item")
+	flags.IntVar(&opts.width, "width", 80, "wrap comments at `n` characters in text output")
+	flags.BoolVar(&cfg.AllDecls, "u", false, "include unexported declarations")
+	flags.StringVar(&cfg.Dir, "C", "", "resolve packages relative to `dir`")
+	functions := flags.Bool("functions", false, "list the functions in each package")
+	types := flags.Bool("types", false, "list the types in each package")
+	errs := flags.Bool("errors", false, "list the errors in each package")
+	imports := flags.Bool("imports", false, "list the imports of each package")
+	tags := flags.String("tags", "", "comma-separated list of build `tags`")
+
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+
+	for list, set := range map[string]bool{"functions": *functions, "types": *types, "errors": *errs, "imports": *imports} {
+		if !set {
+			continue
+		}
+		if opts.list != "" {
+			fmt.Fprintln(stderr, "pkgdoc: only one of -functions, -types, -errors, and -imports may be used")
+			return exitUsage
+		}
+		opts.list = list
+	}
+
+	switch opts.format {
+	case "text", "json", "markdown":
+	default:
+		fmt.Fprintf(stderr, "pkgdoc: unknown format %q\n", opts.format)
+		return exitUsage
+	}
+
+	if *tags != "" {
+		cfg.BuildFlags = []string{"-tags=" + *tags}
+	}
+
+	// Only the files for this system and the given tags are documented if -tags is used.
+	load := func(path string) (pkg.Package, error) {
+		p, err := cfg.Load(path)
+		if err != nil || *tags == "" {
+			return p, err
+		}
+
+		buildTags := strings.Split(*tags, ",")
+		if build.Default.CgoEnabled {
+			buildTags = append(buildTags, "cgo")
+		}
+
+		return p.For(runtime.GOOS, runtime.GOARCH, buildTags...), nil
+	}
+
+	paths, symbol := splitArgs(flags.Args(), opts.list != "")
+	if len(paths) == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	// A lower-case second argument is a symbol unless the package has no such symbol and the argument
+	// names a package instead, like "errors" in "pkgdoc io errors".
+	loaded := make(map[string]pkg.Package)
+	if symbol != "" && !isExported(symbol) {
+		if p, err := load(paths[0]); err == nil {
+			loaded[paths[0]] = p
+			if _, ok := p.Lookup(symbol); !ok {
+				if q, err := load(symbol); err == nil {
+					loaded[symbol] = q
+					paths, symbol = append(paths, symbol), ""
+				}
+			}
+		}
+	}
+
+	code := exitOK
+	fail := func(c int, err error) {
+		fmt.Fprintf(stderr, "pkgdoc: %v\n", err)
+		if code == exitOK {
+			code = c
+		}
+	}
+
+	for i, path := range paths {
+		p, ok := loaded[path]
+		var err error
+		if !ok {
+			p, err = load(path)
+		}
+		if errors.Is(err, pkg.ErrNotFound) {
+			fail(exitNotFound, err)
+			continue
+		} else if err != nil {
+			fail(exitInvalid, err)
+			continue
+		}
+
+		buf := new(bytes.Buffer)
+		if symbol != "" {
+			sym, ok := p.Lookup(symbol)
+			if !ok {
+				fail(exitNotFound, fmt.Errorf("no symbol %s in package %s", symbol, path))
+				continue
+			}
+			err = writeSymbol(buf, sym, opts)
+		} else if opts.list != "" {
+			err = writeList(buf, p, opts, len(paths) > 1)
+		} else {
+			err = writePackage(buf, p, opts)
+		}
+		if err != nil {
+			fail(exitError, err)
+			continue
+		}
+
+		// Separate the documentation for each package with a blank line.
+		if i > 0 && opts.format != "json" && opts.list == "" {
+			buf = bytes.NewBuffer(append([]byte("\n"), buf.Bytes()...))
+		}
+		if _, err := buf.WriteTo(stdout); err != nil {
+			fail(exitError, fmt.Errorf("error writing output: %w", err))
+			break
+		}
+	}
+
+	return code
+}
+
+// splitArgs splits the arguments into the packages to load and the symbol to print, if any. Only the
+// second of exactly two arguments can be a symbol, and only if it is a name or has the form
+// "Type.Method" and no list was requested. Anything else is a package.
+func splitArgs(args []string, list bool) ([]string, string) {
+	if len(args) != 2 || list || !symbolPattern.MatchString(args[1]) {
+		return args, ""
+	}
+
+	return args[:1], args[1]
+}
+
+// isExported reports whether or not a symbol's name, like "Dial" or "client.Call", begins with an
+// upper-case letter.
+func isExported(symbol string) bool {
+	r, _ := utf8.DecodeRuneInString(symbol)
+
+	return unicode.IsUpper(r)
+}
+
+// functions returns all of the functions in p, including the constructors grouped under its types.
+func functions(p pkg.Package) []pkg.Function {
+	list := p.Functions()
+	for _, t := range p.Types() {
+		list = append(list, t.Functions()...)
+	}
+
+	return list
+}
+
+// writePackage writes the documentation for p in the format in opts.
+func writePackage(w io.Writer, p pkg.Package, opts options) error {
+	switch opts.format {
+	case "json":
+		return writeJSON(w, p)
+	case "markdown":
+		return pkg.RenderMarkdown(w, p, pkg.MarkdownOptions{})
+	default:
+		return pkg.RenderText(w, p, textOptions(opts))
+	}
+}
+
+// writeSymbol writes the documentation for a symbol in the format in opts.
+func writeSymbol(w io.Writer, sym pkg.Symbol, opts options) error {
+	if opts.format == "json" {
+		return writeJSON(w, sym.Value())
+	}
+
+	if opts.format == "text" {
+		switch v := sym.Value().(type) {
+		case pkg.Type:
+			return pkg.RenderTypeText(w, v, textOptions(opts))
+		case pkg.Function:
+			return pkg.RenderFunctionText(w, v, textOptions(opts))
+		case pkg.Method:
+			return pkg.RenderMethodText(w, v, textOptions(opts))
+		}
+	}
+
+	// Comments in text output are indented below the source.
+	width := 0
+	if opts.format == "text" {
+		width = opts.width - 4
+	}
+	source, comments := sym.Source(), sym.Comments(width)
+
+	source = strings.TrimRight(source, "\n")
+	if opts.format == "markdown" {
+		_, err := fmt.Fprintf(w, "```go\n%s\n```\n\n%s", source, comments)
+		return err
+	}
+
+	// Everything else is written the same way as go doc writes every declaration: the source,
+	// followed by the indented documentation.
+	sb := new(strings.Builder)
+	sb.WriteString(source + "\n")
+	if comments != "" {
+		for _, line := range strings.Split(strings.TrimRight(comments, "\n"), "\n") {
+			if line != "" {
+				line = "    " + line
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	sb.WriteString("\n")
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// writeList writes one of the lists of items in p in the format in opts. In text output, each item
+// is prefixed with the package's import path if prefix is true.
+func writeList(w io.Writer, p pkg.Package, opts options, prefix bool) error {
+	if opts.format == "json" {
+		switch opts.list {
+		case "functions":
+			return writeJSON(w, functions(p))
+		case "types":
+			return writeJSON(w, p.Types())
+		case "errors":
+			var list []pkg.Error
+			for _, vb := range p.VariableBlocks() {
+				list = append(list, vb.Errors()...)
+			}
+			return writeJSON(w, struct {
+				Errors     []pkg.Error `json:"errors"`
+				ErrorTypes []pkg.Type  `json:"errorTypes"`
+			}{list, p.ErrorTypes()})
+		default:
+			return writeJSON(w, p.Imports())
+		}
+	}
+
+	var lines []string
+	summary := opts
+	summary.short = true
+	switch opts.list {
+	case "functions":
+		for _, f := range functions(p) {
+			sb := new(strings.Builder)
+			if err := pkg.RenderFunctionText(sb, f, textOptions(summary)); err != nil {
+				return err
+			}
+			lines = append(lines, strings.TrimSpace(sb.String()))
+		}
+	case "types":
+		for _, t := range p.Types() {
+			sb := new(strings.Builder)
+			if err := pkg.RenderTypeText(sb, t, textOptions(summary)); err != nil {
+				return err
+			}
+			lines = append(lines, strings.TrimSpace(sb.String()))
+		}
+	case "errors":
+		for _, vb := range p.VariableBlocks() {
+			for _, e := range vb.Errors() {
+				if e.Message() != "" {
+					lines = append(lines, fmt.Sprintf("%s %q", e.Name(), e.Message()))
+				} else {
+					lines = append(lines, e.Name())
+				}
+			}
+		}
+		for _, t := range p.ErrorTypes() {
+			sb := new(strings.Builder)
+			if err := pkg.RenderTypeText(sb, t, textOptions(summary)); err != nil {
+				return err
+			}
+			lines = append(lines, strings.TrimSpace(sb.String()))
+		}
+	default:
+		lines = p.Imports()
+	}
+
+	sb := new(strings.Builder)
+	if opts.format == "markdown" {
+		fmt.Fprintf(sb, "# %s\n\n", p.ImportPath())
+		for _, line := range lines {
+			fmt.Fprintf(sb, "- `%s`\n", line)
+		}
+		sb.WriteString("\n")
+	} else {
+		for _, line := range lines {
+			if prefix {
+				sb.WriteString(p.ImportPath() + ": ")
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("error writing JSON: %w", err)
+	}
+
+	return nil
+}
+
+// textOptions returns the options for the library's text renderer.
+func textOptions(opts options) pkg.TextOptions {
+	return pkg.TextOptions{
+		Width: opts.width,
+		All:   opts.all,
+		Short: opts.short,
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	// Set up a module with a package that cannot be parsed.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/broken\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.go"), []byte("package broken\n\nfunc {\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Set up a module with an unexported function and a file that is only built with a tag.
+	tagged := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod": "module example.com/tagged\n\ngo 1.21\n",
+		"a.go":   "package tagged\n\n// Always is always built.\nfunc Always() {}\n\nfunc helper() {}\n",
+		"foo.go": "//go:build foo\n\npackage tagged\n\n// OnlyFoo is only built with foo.\nfunc OnlyFoo() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(tagged, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{"package", []string{"net/rpc"}, exitOK, `package rpc // import "net/rpc"`},
+		{"method", []string{"net/rpc", "Client.Call"}, exitOK, "func (client *Client) Call("},
+		{"type", []string{"net/rpc", "ServerError"}, exitOK, "type ServerError string"},
+		{"field", []string{"net/rpc", "Request.ServiceMethod"}, exitOK, "ServiceMethod string"},
+		{"constant", []string{"io", "SeekStart"}, exitOK, "SeekCurrent = 1"},
+		{"functions", []string{"-functions", "net/rpc"}, exitOK, "func Dial(network, address string) (*Client, error)\n"},
+		{"errors", []string{"-errors", "net/rpc"}, exitOK, `ErrShutdown "connection is shut down"`},
+		{"imports", []string{"-imports", "net/rpc", "errors"}, exitOK, "net/rpc: encoding/gob\n"},
+		{"json", []string{"-format", "json", "net/rpc", "Dial"}, exitOK, `"name": "Dial"`},
+		{"markdown", []string{"-format", "markdown", "-types", "net/rpc"}, exitOK, "- `type Call struct{ ... }`"},
+		{"missing package", []string{"example.com/missing/pkg"}, exitNotFound, ""},
+		{"missing symbol", []string{"net/rpc", "Missing"}, exitNotFound, ""},
+		{"unexported symbol", []string{"-u", "-C", tagged, ".", "helper"}, exitOK, "func helper()"},
+		{"two packages", []string{"io", "errors"}, exitOK, `package errors // import "errors"`},
+		{"tags", []string{"-C", tagged, "-tags", "foo", "-functions", "."}, exitOK, "func OnlyFoo()"},
+		{"invalid package", []string{"-C", dir, "."}, exitInvalid, ""},
+		{"unknown format", []string{"-format", "xml", "net/rpc"}, exitUsage, ""},
+		{"two lists", []string{"-types", "-errors", "net/rpc"}, exitUsage, ""},
+		{"no arguments", nil, exitUsage, ""},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			if code := run(test.args, stdout, stderr); code != test.code {
+				t.Errorf("%s: incorrect exit code (want %v, have %v): %s", test.name, test.code, code, stderr)
+			}
+			if !strings.Contains(stdout.String(), test.want) {
+				t.Errorf("%s: output missing %q:\n%s", test.name, test.want, stdout)
+			}
+		})
+	}
+
+	// Symbols that are not found should not print their package, and files that are not built with
+	// the given tags should be left out.
+	for _, args := range [][]string{
+		{"net/rpc", "nosuch"},
+		{"-C", tagged, "-tags", "bar", "-functions", "."},
+	} {
+		stdout := new(bytes.Buffer)
+		run(args, stdout, new(bytes.Buffer))
+		if strings.Contains(stdout.String(), "package rpc") || strings.Contains(stdout.String(), "OnlyFoo") {
+			t.Errorf("%v: incorrect output:\n%s", args, stdout)
+		}
+	}
+}
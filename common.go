@@ -53,3 +53,17 @@ func extractSource(node ast.Node, fset *token.FileSet) string {
 
 	return s
 }
+
+// extractFuncSource extracts the source for a function or method's signature, leaving out its
+// documentation and body.
+func extractFuncSource(decl *ast.FuncDecl, fset *token.FileSet) string {
+	if decl == nil {
+		return ""
+	}
+
+	signature := *decl
+	signature.Doc = nil
+	signature.Body = nil
+
+	return extractSource(&signature, fset)
+}
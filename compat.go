@@ -0,0 +1,184 @@
+// This file contains the logic for classifying API changes under Go's compatibility rules.
+package pkg
+
+import (
+	"strings"
+)
+
+// Bump is the part of a semantic version that must be increased for a release.
+type Bump int
+
+const (
+	// Patch means that the release is compatible and does not add to the API, like when only the
+	// value of a constant or the message of an error changes.
+	Patch Bump = iota + 1
+
+	// Minor means that the release is compatible but adds to the API, like a new function, type,
+	// field, or method.
+	Minor
+
+	// Major means that the release is not compatible, like when a declaration is removed, the type
+	// of a parameter changes, or a method is added to an interface.
+	Major
+)
+
+// classify sets the version bump required by the change and the reason for it, following Go's
+// compatibility rules for existing code that uses the package.
+func classify(c *Change, oldPkg Package, newPkg Package) {
+	c.bump, c.reason = Major, "signature changed"
+
+	switch {
+	case c.kind == Removed:
+		c.reason = "removed"
+	case c.kind == Added && c.object == "method" && isInterface(newPkg, typeOf(c.name)):
+		// Types that implemented the interface no longer do.
+		c.reason = "added to interface"
+	case c.kind == Added:
+		c.bump, c.reason = Minor, "added"
+	case c.object == "constant":
+		oldConst, _ := findConstant(oldPkg, c.name)
+		newConst, _ := findConstant(newPkg, c.name)
+		if oldConst.typeName == newConst.typeName {
+			c.bump, c.reason = Patch, "value changed"
+		} else {
+			c.reason = "type changed"
+		}
+	case c.object == "error":
+		// Errors are compared by identity, not by their messages.
+		c.bump, c.reason = Patch, "message changed"
+	case c.object == "type" && strings.TrimSuffix(c.old, unexportedMarker) == strings.TrimSuffix(c.new, unexportedMarker):
+		// Only the interface's unexported methods changed. Types in other packages could implement the
+		// interface before it had unexported methods, but not after.
+		if strings.HasSuffix(c.new, unexportedMarker) {
+			c.reason = "unexported methods added to interface"
+		} else {
+			c.bump, c.reason = Minor, "unexported methods removed from interface"
+		}
+	case c.object == "variable", c.object == "type", c.object == "field":
+		c.reason = "type changed"
+	case c.object == "method" && !isInterface(newPkg, typeOf(c.name)):
+		oldMethod, _ := findMethod(oldPkg, c.name)
+		newMethod, _ := findMethod(newPkg, c.name)
+		if signature(nil, oldMethod.inputs, oldMethod.outputs) != signature(nil, newMethod.inputs, newMethod.outputs) {
+			break
+		}
+
+		// Only the receiver changed. Values of the type lose the method if the receiver becomes a
+		// pointer, but they gain the method if the receiver becomes a value.
+		if oldMethod.PointerReceiver() && !newMethod.PointerReceiver() {
+			c.bump, c.reason = Minor, "receiver changed from pointer to value"
+		} else {
+			c.reason = "receiver changed from value to pointer"
+		}
+	}
+}
+
+// typeOf returns the name of the type in a qualified name like "File.Close", or "" if the name is
+// not qualified.
+func typeOf(name string) string {
+	typeName, _, ok := strings.Cut(name, ".")
+	if !ok {
+		return ""
+	}
+
+	return typeName
+}
+
+// isInterface reports whether or not the type with the given name in p is an interface.
+func isInterface(p Package, name string) bool {
+	for _, t := range p.types {
+		if t.name == name {
+			return t.typeName == "interface"
+		}
+	}
+
+	return false
+}
+
+// findConstant returns the constant with the given name in p.
+func findConstant(p Package, name string) (Constant, bool) {
+	for _, cb := range p.constantBlocks {
+		for _, c := range cb.constants {
+			if c.name == name {
+				return c, true
+			}
+		}
+	}
+
+	return Constant{}, false
+}
+
+// findMethod returns the method with the given qualified name, like "File.Close", in p.
+func findMethod(p Package, name string) (Method, bool) {
+	typeName := typeOf(name)
+	for _, t := range p.types {
+		if t.name != typeName {
+			continue
+		}
+		for _, m := range t.methods {
+			if typeName+"."+m.name == name {
+				return m, true
+			}
+		}
+	}
+
+	return Method{}, false
+}
+
+// Bump returns the version bump that the report's changes require: Major if any change is not
+// compatible, Minor if any change adds to the API, and Patch otherwise (including when nothing
+// changed).
+func (r Report) Bump() Bump {
+	bump := Patch
+	for _, c := range r.changes {
+		if c.bump > bump {
+			bump = c.bump
+		}
+	}
+
+	return bump
+}
+
+// Breaking returns every change in the report that is not compatible with the old version of the
+// package.
+func (r Report) Breaking() []Change {
+	changes := make([]Change, 0)
+	for _, c := range r.changes {
+		if c.Breaking() {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes
+}
+
+// Bump returns the version bump that the change requires under Go's compatibility rules.
+func (c Change) Bump() Bump {
+	return c.bump
+}
+
+// Breaking reports whether or not the change is incompatible with the old version of the package,
+// meaning that code which used the old version might no longer build.
+func (c Change) Breaking() bool {
+	return c.bump == Major
+}
+
+// Reason returns the reason for the change's version bump, like "removed", "added to interface", or
+// "receiver changed from value to pointer".
+func (c Change) Reason() string {
+	return c.reason
+}
+
+// String returns the name of the version bump: "patch", "minor", or "major".
+func (b Bump) String() string {
+	switch b {
+	case Patch:
+		return "patch"
+	case Minor:
+		return "minor"
+	case Major:
+		return "major"
+	default:
+		return "unknown"
+	}
+}
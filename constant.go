@@ -2,8 +2,13 @@
 package pkg
 
 import (
+	"go/ast"
+	"go/constant"
 	"go/doc"
 	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
 )
 
 // ConstantBlock holds information about a block of one or more (grouped) exported constants in a
@@ -20,16 +25,37 @@ type ConstantBlock struct {
 
 	// List of constants within this block.
 	constants []Constant
+
+	// Location of this block's declaration.
+	position Position
+
+	// Build constraint that this block is declared under, or "" if it is built everywhere.
+	constraint string
 }
 
 // Constant holds information about a single exported constant within a block.
 type Constant struct {
 	// Name of this constant.
 	name string
+
+	// Name of this constant's type, like "int", "Duration", or "untyped rune".
+	typeName string
+
+	// Value of this constant, or nil if the value could not be determined.
+	value constant.Value
+
+	// Comments for this constant.
+	comments string
+
+	// Location of this constant's declaration.
+	position Position
+
+	// Build constraint that this constant is declared under, or "" if it is built everywhere.
+	constraint string
 }
 
 // newConstantBlock builds a new ConstantBlock object based on go/doc's Value.
-func newConstantBlock(v *doc.Value, t *doc.Type, fset *token.FileSet) ConstantBlock {
+func newConstantBlock(v *doc.Value, t *doc.Type, fset *token.FileSet, ti *typeInfo) ConstantBlock {
 	if v == nil {
 		return ConstantBlock{}
 	}
@@ -44,9 +70,19 @@ func newConstantBlock(v *doc.Value, t *doc.Type, fset *token.FileSet) ConstantBl
 	source := extractSource(v.Decl, fset)
 
 	// Build the list of individual constants.
-	constants := make([]Constant, len(v.Names))
-	for i, n := range v.Names {
-		constants[i] = Constant{name: n}
+	constants := make([]Constant, 0, len(v.Names))
+	for _, spec := range v.Decl.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		for i, name := range vs.Names {
+			// go/doc replaces unexported names that it can't remove with the blank identifier.
+			if name.Name == "_" {
+				continue
+			}
+			constants = append(constants, newConstant(vs, i, name, fset, ti))
+		}
 	}
 
 	return ConstantBlock{
@@ -54,7 +90,62 @@ func newConstantBlock(v *doc.Value, t *doc.Type, fset *token.FileSet) ConstantBl
 		comments:  v.Doc,
 		source:    source,
 		constants: constants,
+		position:  newPosition(v.Decl.Pos(), v.Decl.End(), fset),
+	}
+}
+
+// newConstant builds a new Constant object for the constant declared by name, which is the ith name
+// in the value specification vs.
+func newConstant(vs *ast.ValueSpec, i int, name *ast.Ident, fset *token.FileSet, ti *typeInfo) Constant {
+	c := Constant{
+		name:     name.Name,
+		comments: specComments(vs),
+		position: newPosition(name.Pos(), vs.End(), fset),
 	}
+
+	// The type checker resolves iota, implicit repetition, and typed expressions for us.
+	if ti != nil {
+		if obj, ok := ti.info.Defs[name].(*types.Const); ok {
+			c.typeName = ti.typeString(obj.Type())
+			c.value = obj.Val()
+
+			return c
+		}
+	}
+
+	// Without type information, we can only read out what is written in source.
+	if vs.Type != nil {
+		c.typeName = strings.TrimSpace(extractSource(vs.Type, fset))
+	}
+	if i < len(vs.Values) {
+		if lit, ok := vs.Values[i].(*ast.BasicLit); ok {
+			c.value = constant.MakeFromLiteral(lit.Value, lit.Kind, 0)
+			if c.typeName == "" {
+				c.typeName = untypedNames[lit.Kind]
+			}
+		}
+	}
+
+	return c
+}
+
+// untypedNames maps the kind of a literal to the name of its untyped type.
+var untypedNames = map[token.Token]string{
+	token.INT:    "untyped int",
+	token.FLOAT:  "untyped float",
+	token.IMAG:   "untyped complex",
+	token.CHAR:   "untyped rune",
+	token.STRING: "untyped string",
+}
+
+// specComments returns the comments for a single value specification. The comments above the
+// specification are preferred over the comment on the same line.
+func specComments(vs *ast.ValueSpec) string {
+	if vs.Doc != nil {
+		return vs.Doc.Text()
+	}
+
+	return vs.Comment.Text()
 }
 
 // Type returns the name of the general, non-built-in type for this block of constants, or "" if this block
@@ -73,6 +164,17 @@ func (cb ConstantBlock) Source() string {
 	return cb.source
 }
 
+// Position returns the location of the declaration for this block of constants.
+func (cb ConstantBlock) Position() Position {
+	return cb.position
+}
+
+// Constraint returns the build constraint that the block of constants is declared under, like
+// "linux && amd64", or "" if it is built everywhere.
+func (cb ConstantBlock) Constraint() string {
+	return cb.constraint
+}
+
 // Constants returns a list of constants in this block of constants.
 func (cb ConstantBlock) Constants() []Constant {
 	return append([]Constant{}, cb.constants...)
@@ -82,3 +184,64 @@ func (cb ConstantBlock) Constants() []Constant {
 func (c Constant) Name() string {
 	return c.name
 }
+
+// Exported reports whether or not this constant is exported.
+func (c Constant) Exported() bool {
+	return ast.IsExported(c.name)
+}
+
+// Type returns the name of the constant's type, like "int" or "Duration". Untyped constants report
+// their default untyped type, like "untyped int" or "untyped rune".
+func (c Constant) Type() string {
+	return c.typeName
+}
+
+// Value returns the string representation of the constant's value, like "1114111" or "\"UTC\"".
+// Rune constants are printed as rune literals, like '\U0010ffff', and floating-point constants are
+// printed as the closest float64 value. For the exact value, see Constant's ExactValue. This returns
+// "" if the value could not be determined.
+func (c Constant) Value() string {
+	if c.value == nil {
+		return ""
+	}
+
+	switch c.value.Kind() {
+	case constant.Int:
+		if c.typeName == "rune" || c.typeName == "untyped rune" {
+			if r, ok := constant.Int64Val(c.value); ok {
+				return strconv.QuoteRune(rune(r))
+			}
+		}
+	case constant.Float:
+		f, _ := constant.Float64Val(c.value)
+
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	return c.value.ExactString()
+}
+
+// ExactValue returns the constant's exact value as computed by the type checker, or nil if the value
+// could not be determined (for example, if the package could not be type-checked).
+func (c Constant) ExactValue() constant.Value {
+	return c.value
+}
+
+// Position returns the location of the constant's declaration, from the constant's name to the end of
+// its specification.
+func (c Constant) Position() Position {
+	return c.position
+}
+
+// Constraint returns the build constraint that the constant is declared under, like
+// "linux && amd64", or "" if it is built everywhere.
+func (c Constant) Constraint() string {
+	return c.constraint
+}
+
+// Comments returns the documentation for this constant with pkg's formatting applied. This is either
+// the comment above the constant's specification or the comment on the same line. For the
+// documentation of the whole block, see ConstantBlock's Comments.
+func (c Constant) Comments(width int) string {
+	return formatComments(c.comments, width)
+}
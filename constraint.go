@@ -0,0 +1,373 @@
+// This file contains the logic for build constraints on files and declarations.
+package pkg
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// noTag is a GOOS and GOARCH that never matches a build constraint.
+const noTag = "-"
+
+// fileConstraint returns the build constraint for a source file, or nil if the file is built
+// everywhere. This combines the file's //go:build line (or its // +build lines, for older files), the
+// operating system and architecture in its name (like "file_linux_amd64.go"), and cgo if it imports
+// "C".
+func fileConstraint(name string, f *ast.File) constraint.Expr {
+	var exprs []constraint.Expr
+
+	if x := fileNameConstraint(name); x != nil {
+		exprs = append(exprs, x)
+	}
+
+	// Build constraints must appear before the package clause. The package's documentation cannot
+	// hold constraints.
+	var goBuild constraint.Expr
+	var plusBuild []constraint.Expr
+	for _, cg := range f.Comments {
+		if cg.End() >= f.Package {
+			break
+		}
+		if cg == f.Doc {
+			continue
+		}
+		for _, c := range cg.List {
+			x, err := constraint.Parse(c.Text)
+			switch {
+			case err != nil:
+				continue
+			case constraint.IsGoBuild(c.Text):
+				goBuild = x
+			case constraint.IsPlusBuild(c.Text):
+				plusBuild = append(plusBuild, x)
+			}
+		}
+	}
+	if goBuild != nil {
+		exprs = append(exprs, goBuild)
+	} else {
+		exprs = append(exprs, plusBuild...)
+	}
+
+	for _, imp := range f.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); path == "C" {
+			exprs = append(exprs, &constraint.TagExpr{Tag: "cgo"})
+			break
+		}
+	}
+
+	var x constraint.Expr
+	for _, e := range exprs {
+		if x == nil {
+			x = e
+		} else {
+			x = &constraint.AndExpr{X: x, Y: e}
+		}
+	}
+
+	return x
+}
+
+// fileNameConstraint returns the build constraint implied by a file's name, like "linux && amd64" for
+// "file_linux_amd64.go", or nil if the name does not restrict the file. This follows the same rules as
+// go/build.
+func fileNameConstraint(name string) constraint.Expr {
+	stem := strings.TrimSuffix(strings.TrimSuffix(name, ".go"), "_test")
+	_, suffix, ok := strings.Cut(stem, "_")
+	if !ok {
+		return nil
+	}
+
+	parts := strings.Split(suffix, "_")
+	n := len(parts)
+	switch {
+	case n >= 2 && isKnownOS(parts[n-2]) && isKnownArch(parts[n-1]):
+		return &constraint.AndExpr{X: &constraint.TagExpr{Tag: parts[n-2]}, Y: &constraint.TagExpr{Tag: parts[n-1]}}
+	case isKnownOS(parts[n-1]) || isKnownArch(parts[n-1]):
+		return &constraint.TagExpr{Tag: parts[n-1]}
+	default:
+		return nil
+	}
+}
+
+// isKnownOS reports whether or not go/build recognizes s as an operating system in file names.
+func isKnownOS(s string) bool {
+	name := "file_" + s + ".go"
+
+	return !matchesFile(name, "", noTag, noTag, nil) && matchesFile(name, "", s, noTag, nil)
+}
+
+// isKnownArch reports whether or not go/build recognizes s as an architecture in file names.
+func isKnownArch(s string) bool {
+	name := "file_" + s + ".go"
+
+	return !matchesFile(name, "", noTag, noTag, nil) && matchesFile(name, "", noTag, s, nil)
+}
+
+// matchConstraint reports whether or not a build constraint, like "linux && !purego", is satisfied
+// for goos, goarch, and tags. An empty constraint is always satisfied.
+func matchConstraint(expr string, goos string, goarch string, tags []string) bool {
+	if expr == "" {
+		return true
+	}
+
+	return matchesFile("constraint.go", "//go:build "+expr+"\n\n", goos, goarch, tags)
+}
+
+// matchesFile reports whether or not go/build would build a file with the given name and header for
+// goos, goarch, and tags. Release tags for the current version of Go and the default compiler's tag
+// are always satisfied. cgo is only enabled if it is one of the tags.
+func matchesFile(name string, header string, goos string, goarch string, tags []string) bool {
+	ctx := sourcesContext(map[string][]byte{name: []byte(header + "package p\n")})
+	ctx.GOOS = goos
+	ctx.GOARCH = goarch
+	ctx.BuildTags = tags
+	ctx.CgoEnabled = false
+
+	ok, err := ctx.MatchFile(sourcesDir, name)
+
+	return err == nil && ok
+}
+
+// orConstraints returns the constraint under which at least one of exprs is satisfied, or nil if any
+// of them is nil (meaning always satisfied).
+func orConstraints(exprs []constraint.Expr) constraint.Expr {
+	var x constraint.Expr
+	seen := make(map[string]bool)
+	for _, e := range exprs {
+		if e == nil {
+			return nil
+		}
+		if seen[e.String()] {
+			continue
+		}
+		seen[e.String()] = true
+
+		if x == nil {
+			x = e
+		} else {
+			x = &constraint.OrExpr{X: x, Y: e}
+		}
+	}
+
+	return x
+}
+
+// receiverName returns the name of the type in a method's receiver, like "List" for "l *List[T]".
+func receiverName(recv *ast.FieldList) string {
+	if recv == nil || len(recv.List) == 0 {
+		return ""
+	}
+
+	x := recv.List[0].Type
+	if star, ok := x.(*ast.StarExpr); ok {
+		x = star.X
+	}
+	switch r := x.(type) {
+	case *ast.IndexExpr:
+		x = r.X
+	case *ast.IndexListExpr:
+		x = r.X
+	}
+	if ident, ok := x.(*ast.Ident); ok {
+		return ident.Name
+	}
+
+	return ""
+}
+
+// buildConstraints holds the build constraints for a package's source files and declarations.
+type buildConstraints struct {
+	// Constraints for the files that have them, keyed by file name.
+	files map[string]string
+
+	// Constraints for each function, type, and method, keyed by name (like "Open" or "File.Close").
+	symbols map[string]string
+}
+
+// newBuildConstraints finds the build constraint for each of the package's source files in goFiles and
+// for each function, type, and method declared in them. Functions, types, and methods can be declared
+// in more than one file (like once for each operating system), so their constraint is satisfied if any
+// of those files' constraints is. This must be called before go/doc removes the files' comments.
+func newBuildConstraints(goFiles map[string]*ast.File) buildConstraints {
+	bc := buildConstraints{
+		files:   make(map[string]string),
+		symbols: make(map[string]string),
+	}
+
+	paths := make([]string, 0, len(goFiles))
+	for path := range goFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	declared := make(map[string][]constraint.Expr)
+	for _, path := range paths {
+		name := filepath.Base(path)
+		x := fileConstraint(name, goFiles[path])
+		if x != nil {
+			bc.files[name] = x.String()
+		}
+
+		for _, decl := range goFiles[path].Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				name := d.Name.Name
+				if recv := receiverName(d.Recv); recv != "" {
+					name = recv + "." + name
+				}
+				declared[name] = append(declared[name], x)
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						declared[ts.Name.Name] = append(declared[ts.Name.Name], x)
+					}
+				}
+			}
+		}
+	}
+
+	for name, exprs := range declared {
+		if x := orConstraints(exprs); x != nil {
+			bc.symbols[name] = x.String()
+		}
+	}
+
+	return bc
+}
+
+// apply annotates every declaration in the package with the constraint it is declared under.
+func (bc buildConstraints) apply(pkg *Package) {
+	pkg.constraints = bc.files
+
+	// Blocks of constants and variables are kept separate for each file, so they only have the
+	// constraint of the file they are in.
+	for i := range pkg.constantBlocks {
+		cb := &pkg.constantBlocks[i]
+		cb.constraint = bc.files[cb.position.file]
+		for j := range cb.constants {
+			cb.constants[j].constraint = cb.constraint
+		}
+	}
+	for i := range pkg.variableBlocks {
+		vb := &pkg.variableBlocks[i]
+		vb.constraint = bc.files[vb.position.file]
+		for j := range vb.variables {
+			vb.variables[j].constraint = vb.constraint
+		}
+		for j := range vb.errors {
+			vb.errors[j].constraint = vb.constraint
+		}
+	}
+
+	for i := range pkg.functions {
+		pkg.functions[i].constraint = bc.symbols[pkg.functions[i].name]
+	}
+	for i := range pkg.types {
+		t := &pkg.types[i]
+		t.constraint = bc.symbols[t.name]
+		for j := range t.functions {
+			t.functions[j].constraint = bc.symbols[t.functions[j].name]
+		}
+		for j := range t.methods {
+			t.methods[j].constraint = bc.symbols[t.name+"."+t.methods[j].name]
+		}
+		for j := range t.interfaceMethods {
+			t.interfaceMethods[j].constraint = t.constraint
+		}
+		for j := range t.methodSet {
+			t.methodSet[j].constraint = t.constraint
+		}
+	}
+}
+
+// Constraints returns the build constraint for each of the package's source files that has one, keyed
+// by file name. Each constraint combines the file's //go:build line with the operating system and
+// architecture in its name and cgo if it imports "C", like "linux && amd64 && !purego". Files that are
+// built everywhere are not included.
+func (p Package) Constraints() map[string]string {
+	m := make(map[string]string, len(p.constraints))
+	for name, expr := range p.constraints {
+		m[name] = expr
+	}
+
+	return m
+}
+
+// For returns a copy of the package with only the source files and declarations that are built for
+// the given operating system, architecture, and build tags, like For("linux", "amd64", "purego").
+// Release tags for the current version of Go (like "go1.21") and the default compiler's tag are always
+// satisfied, and cgo is only enabled if "cgo" is one of the tags. Test files, tests, and examples are
+// not filtered.
+func (p Package) For(goos string, goarch string, tags ...string) Package {
+	cache := make(map[string]bool)
+	match := func(expr string) bool {
+		ok, found := cache[expr]
+		if !found {
+			ok = matchConstraint(expr, goos, goarch, tags)
+			cache[expr] = ok
+		}
+
+		return ok
+	}
+
+	q := p
+	q.files = make([]string, 0, len(p.files))
+	for _, file := range p.files {
+		if match(p.constraints[file]) {
+			q.files = append(q.files, file)
+		}
+	}
+
+	q.constantBlocks = make([]ConstantBlock, 0, len(p.constantBlocks))
+	for _, cb := range p.constantBlocks {
+		if match(cb.constraint) {
+			q.constantBlocks = append(q.constantBlocks, cb)
+		}
+	}
+
+	q.variableBlocks = make([]VariableBlock, 0, len(p.variableBlocks))
+	for _, vb := range p.variableBlocks {
+		if match(vb.constraint) {
+			q.variableBlocks = append(q.variableBlocks, vb)
+		}
+	}
+
+	q.functions = make([]Function, 0, len(p.functions))
+	for _, f := range p.functions {
+		if match(f.constraint) {
+			q.functions = append(q.functions, f)
+		}
+	}
+
+	q.types = make([]Type, 0, len(p.types))
+	for _, t := range p.types {
+		if !match(t.constraint) {
+			continue
+		}
+
+		functions := make([]Function, 0, len(t.functions))
+		for _, f := range t.functions {
+			if match(f.constraint) {
+				functions = append(functions, f)
+			}
+		}
+		t.functions = functions
+
+		methods := make([]Method, 0, len(t.methods))
+		for _, m := range t.methods {
+			if match(m.constraint) {
+				methods = append(methods, m)
+			}
+		}
+		t.methods = methods
+
+		q.types = append(q.types, t)
+	}
+
+	return q
+}
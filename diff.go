@@ -0,0 +1,468 @@
+// This file contains the logic for finding the changes in a package's API between two versions.
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of a change in a package's API.
+type ChangeKind int
+
+const (
+	// Added means that the declaration only exists in the new version of the package.
+	Added ChangeKind = iota + 1
+
+	// Removed means that the declaration only exists in the old version of the package.
+	Removed
+
+	// Changed means that the declaration exists in both versions of the package but is different.
+	Changed
+)
+
+// Report holds the changes in a package's API between two versions of the package.
+type Report struct {
+	// List of changes, sorted by name.
+	changes []Change
+}
+
+// Change holds information about a single change to a declaration in a package's API.
+type Change struct {
+	// Kind of this change.
+	kind ChangeKind
+
+	// Kind of declaration that changed, like "function" or "method".
+	object string
+
+	// Name of the declaration that changed. Fields and methods are qualified by their type's name.
+	name string
+
+	// Declaration in the old version of the package.
+	old string
+
+	// Declaration in the new version of the package.
+	new string
+
+	// Version bump that this change requires under Go's compatibility rules.
+	bump Bump
+
+	// Reason for the version bump, like "removed" or "added to interface".
+	reason string
+}
+
+// jsonReport is the JSON representation of a Report.
+type jsonReport struct {
+	Changes []Change `json:"changes"`
+}
+
+// jsonChange is the JSON representation of a Change.
+type jsonChange struct {
+	Kind   string `json:"kind"`
+	Object string `json:"object"`
+	Name   string `json:"name"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+	Bump   string `json:"bump"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Diff compares the exported API of two versions of a package and reports every constant, variable,
+// error, function, type, field, and method that was added, removed, or changed. Functions and methods
+// are compared by the types of their parameters, so renaming a parameter is not a change. Interfaces
+// are compared by their full method sets, including the methods of embedded interfaces. The fields
+// and methods of types that were added or removed are not reported separately. Each change is
+// classified by the version bump it requires under Go's compatibility rules (see Change's Bump).
+// Unexported declarations are ignored, even if the packages were loaded with Config's AllDecls, except
+// that an interface gaining or losing unexported methods is reported as a change to its type. Types in
+// other packages cannot implement an interface with unexported methods.
+func Diff(oldPkg Package, newPkg Package) Report {
+	var changes []Change
+	changes = append(changes, diffDecls("constant", constantDecls(oldPkg), constantDecls(newPkg))...)
+	changes = append(changes, diffDecls("variable", variableDecls(oldPkg), variableDecls(newPkg))...)
+	changes = append(changes, diffDecls("error", errorDecls(oldPkg), errorDecls(newPkg))...)
+	changes = append(changes, diffDecls("function", functionDecls(oldPkg), functionDecls(newPkg))...)
+	changes = append(changes, diffDecls("type", typeDecls(oldPkg), typeDecls(newPkg))...)
+
+	// Compare the fields and methods of the types that are in both versions.
+	newTypes := make(map[string]Type, len(newPkg.types))
+	for _, t := range newPkg.types {
+		newTypes[t.name] = t
+	}
+	for _, oldType := range oldPkg.types {
+		newType, ok := newTypes[oldType.name]
+		if !ok || !oldType.Exported() {
+			continue
+		}
+		changes = append(changes, diffDecls("field", fieldDecls(oldType), fieldDecls(newType))...)
+		changes = append(changes, diffDecls("method", methodDecls(oldType), methodDecls(newType))...)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].name < changes[j].name
+	})
+
+	for i := range changes {
+		classify(&changes[i], oldPkg, newPkg)
+	}
+
+	return Report{changes: changes}
+}
+
+// diffDecls compares two sets of declarations, keyed by name, and returns the changes between them.
+func diffDecls(object string, oldDecls map[string]string, newDecls map[string]string) []Change {
+	var changes []Change
+	for name, oldDecl := range oldDecls {
+		newDecl, ok := newDecls[name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{kind: Removed, object: object, name: name, old: oldDecl})
+		case oldDecl != newDecl:
+			changes = append(changes, Change{kind: Changed, object: object, name: name, old: oldDecl, new: newDecl})
+		}
+	}
+	for name, newDecl := range newDecls {
+		if _, ok := oldDecls[name]; !ok {
+			changes = append(changes, Change{kind: Added, object: object, name: name, new: newDecl})
+		}
+	}
+
+	return changes
+}
+
+// constantDecls returns the declaration of every constant in the package, like "const Pi float64 =
+// 3.14". The types of untyped constants are left out, like they are in source.
+func constantDecls(p Package) map[string]string {
+	decls := make(map[string]string)
+	for _, cb := range p.constantBlocks {
+		for _, c := range cb.constants {
+			if !c.Exported() {
+				continue
+			}
+			decl := "const " + c.name
+			if c.typeName != "" && !strings.HasPrefix(c.typeName, "untyped ") {
+				decl += " " + c.typeName
+			}
+			if value := c.Value(); value != "" {
+				decl += " = " + value
+			}
+			decls[c.name] = decl
+		}
+	}
+
+	return decls
+}
+
+// variableDecls returns the declaration of every variable in the package that is not an error, like
+// "var Default Size".
+func variableDecls(p Package) map[string]string {
+	decls := make(map[string]string)
+	for _, vb := range p.variableBlocks {
+		errs := make(map[string]bool, len(vb.errors))
+		for _, e := range vb.errors {
+			errs[e.name] = true
+		}
+		for _, v := range vb.variables {
+			if errs[v.name] || !v.Exported() {
+				continue
+			}
+			decls[v.name] = strings.TrimSpace("var " + v.name + " " + v.typeName)
+		}
+	}
+
+	return decls
+}
+
+// errorDecls returns the declaration of every error in the package with its message, like `var ErrNope
+// error // "nope"`. Errors are compared by their messages.
+func errorDecls(p Package) map[string]string {
+	decls := make(map[string]string)
+	for _, vb := range p.variableBlocks {
+		for _, e := range vb.errors {
+			if !e.Exported() {
+				continue
+			}
+			decl := "var " + e.name + " error"
+			if e.message != "" {
+				decl += fmt.Sprintf(" // %q", e.message)
+			}
+			decls[e.name] = decl
+		}
+	}
+
+	return decls
+}
+
+// functionDecls returns the signature of every function in the package, including the functions
+// grouped under types, like "func Open(string) (*File, error)".
+func functionDecls(p Package) map[string]string {
+	decls := make(map[string]string)
+	for _, f := range p.functions {
+		if f.Exported() {
+			decls[f.name] = "func " + f.name + signature(f.typeParams, f.inputs, f.outputs)
+		}
+	}
+	for _, t := range p.types {
+		for _, f := range t.functions {
+			if f.Exported() {
+				decls[f.name] = "func " + f.name + signature(f.typeParams, f.inputs, f.outputs)
+			}
+		}
+	}
+
+	return decls
+}
+
+// typeDecls returns the declaration of every type in the package without its body, like "type
+// List[T any] struct". Interfaces with unexported methods are marked with unexportedMarker.
+func typeDecls(p Package) map[string]string {
+	decls := make(map[string]string)
+	for _, t := range p.types {
+		if t.Exported() {
+			decls[t.name] = "type " + t.name + typeParamList(t.typeParams) + " " + oneLine(t.typeName)
+			if t.unexportedMethods {
+				decls[t.name] += unexportedMarker
+			}
+		}
+	}
+
+	return decls
+}
+
+// unexportedMarker is added to the declaration of an interface that has unexported methods.
+const unexportedMarker = " // has unexported methods"
+
+// fieldDecls returns the declaration of every exported field in the type, like "File.Name string".
+func fieldDecls(t Type) map[string]string {
+	decls := make(map[string]string)
+	for _, f := range t.fields {
+		if f.Exported() {
+			name := t.name + "." + f.name
+			decls[name] = name + " " + f.typeName
+		}
+	}
+
+	return decls
+}
+
+// methodDecls returns the signature of every exported method of the type, like "func (*File)
+// Close() error". Methods of an interface type have the form "Reader.Read([]byte) (int, error)" and
+// come from its full method set, so adding or removing an embedded interface adds or removes the
+// methods that it brings in.
+func methodDecls(t Type) map[string]string {
+	decls := make(map[string]string)
+	for _, m := range t.methods {
+		if m.Exported() {
+			name := t.name + "." + m.name
+			decls[name] = "func (" + m.receiver.typeName + ") " + m.name + signature(nil, m.inputs, m.outputs)
+		}
+	}
+	for _, m := range t.methodSet {
+		if m.Exported() {
+			name := t.name + "." + m.name
+			decls[name] = name + signature(nil, m.inputs, m.outputs)
+		}
+	}
+
+	return decls
+}
+
+// signature returns the part of a function's signature after its name, with only the types of the
+// parameters, like "[T any](T, ...int) (int, error)".
+func signature(typeParams []TypeParam, inputs []Parameter, outputs []Parameter) string {
+	in := make([]string, len(inputs))
+	for i, p := range inputs {
+		in[i] = p.typeName
+	}
+	out := make([]string, len(outputs))
+	for i, p := range outputs {
+		out[i] = p.typeName
+	}
+
+	s := typeParamList(typeParams) + "(" + strings.Join(in, ", ") + ")"
+	switch len(out) {
+	case 0:
+		return s
+	case 1:
+		return s + " " + out[0]
+	default:
+		return s + " (" + strings.Join(out, ", ") + ")"
+	}
+}
+
+// typeParamList returns a list of type parameters as written in source, like "[K comparable, V any]",
+// or "" if there are none.
+func typeParamList(typeParams []TypeParam) string {
+	if len(typeParams) == 0 {
+		return ""
+	}
+
+	params := make([]string, len(typeParams))
+	for i, tp := range typeParams {
+		params[i] = tp.String()
+	}
+
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+// Changes returns every change in the report, sorted by the name of the declaration that changed.
+func (r Report) Changes() []Change {
+	return append([]Change{}, r.changes...)
+}
+
+// Empty reports whether or not there are no changes in the report.
+func (r Report) Empty() bool {
+	return len(r.changes) == 0
+}
+
+// String returns the report as text suitable for release notes. Changes are grouped into removed,
+// added, and changed declarations, with the old declaration listed below each changed one.
+func (r Report) String() string {
+	sb := new(strings.Builder)
+	for _, kind := range []ChangeKind{Removed, Added, Changed} {
+		first := true
+		for _, c := range r.changes {
+			if c.kind != kind {
+				continue
+			}
+			if first {
+				if sb.Len() > 0 {
+					sb.WriteString("\n")
+				}
+				sb.WriteString(strings.ToUpper(kind.String()[:1]) + kind.String()[1:] + ":\n")
+				first = false
+			}
+			sb.WriteString("- " + c.String() + "\n")
+			if kind == Changed {
+				sb.WriteString("  was " + c.old + "\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// MarshalJSON encodes the report as JSON.
+func (r Report) MarshalJSON() ([]byte, error) {
+	changes := r.changes
+	if changes == nil {
+		changes = []Change{}
+	}
+
+	return marshal(jsonReport{Changes: changes})
+}
+
+// UnmarshalJSON decodes a report that was encoded with Report's MarshalJSON.
+func (r *Report) UnmarshalJSON(data []byte) error {
+	var j jsonReport
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*r = Report{changes: j.Changes}
+
+	return nil
+}
+
+// Kind returns the kind of change.
+func (c Change) Kind() ChangeKind {
+	return c.kind
+}
+
+// Object returns the kind of declaration that changed: "constant", "variable", "error", "function",
+// "type", "field", or "method".
+func (c Change) Object() string {
+	return c.object
+}
+
+// Name returns the name of the declaration that changed. Fields and methods are qualified by their
+// type's name, like "File.Close".
+func (c Change) Name() string {
+	return c.name
+}
+
+// Old returns the declaration in the old version of the package, or "" if it was added.
+func (c Change) Old() string {
+	return c.old
+}
+
+// New returns the declaration in the new version of the package, or "" if it was removed.
+func (c Change) New() string {
+	return c.new
+}
+
+// String returns the declaration that changed as it is in the newest version that has it.
+func (c Change) String() string {
+	if c.kind == Removed {
+		return c.old
+	}
+
+	return c.new
+}
+
+// MarshalJSON encodes the change as JSON.
+func (c Change) MarshalJSON() ([]byte, error) {
+	return marshal(jsonChange{
+		Kind:   c.kind.String(),
+		Object: c.object,
+		Name:   c.name,
+		Old:    c.old,
+		New:    c.new,
+		Bump:   c.bump.String(),
+		Reason: c.reason,
+	})
+}
+
+// UnmarshalJSON decodes a change that was encoded with Change's MarshalJSON.
+func (c *Change) UnmarshalJSON(data []byte) error {
+	var j jsonChange
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	var kind ChangeKind
+	for _, k := range []ChangeKind{Added, Removed, Changed} {
+		if k.String() == j.Kind {
+			kind = k
+		}
+	}
+	if kind == 0 {
+		return fmt.Errorf("json error: unknown kind of change %q", j.Kind)
+	}
+
+	var bump Bump
+	for _, b := range []Bump{Patch, Minor, Major} {
+		if b.String() == j.Bump {
+			bump = b
+		}
+	}
+	if bump == 0 {
+		return fmt.Errorf("json error: unknown version bump %q", j.Bump)
+	}
+
+	*c = Change{
+		kind:   kind,
+		object: j.Object,
+		name:   j.Name,
+		old:    j.Old,
+		new:    j.New,
+		bump:   bump,
+		reason: j.Reason,
+	}
+
+	return nil
+}
+
+// String returns the name of the kind of change: "added", "removed", or "changed".
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,185 @@
+// This file contains the information and logic for the Example type.
+package pkg
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Example holds information about an example function in a package's test files.
+type Example struct {
+	// Example's full name, like "ExampleFile_Close_second".
+	name string
+
+	// Suffix after the symbol's name, like "second", if there is one.
+	suffix string
+
+	// Declaration that this example documents, like "File.Close", or "" for the package.
+	symbol string
+
+	// Comments for this example.
+	comments string
+
+	// Body of this example, without the braces or the output comment.
+	body string
+
+	// Expected output of this example.
+	output string
+
+	// Whether or not the example has an output comment, even if the output is empty.
+	hasOutput bool
+
+	// Whether or not the lines of output can be in any order.
+	unordered bool
+
+	// Location of this example's declaration.
+	position Position
+}
+
+// exampleOutput matches the comment that begins an example's expected output.
+var exampleOutput = regexp.MustCompile(`(?im)^[[:space:]]*//[[:space:]]*(unordered )?output:`)
+
+// newExamples builds a list of Example objects based on go/doc's Examples for the declaration symbol.
+// decls maps the name of every function in the test files to its declaration.
+func newExamples(exs []*doc.Example, symbol string, decls map[string]*ast.FuncDecl, fset *token.FileSet) []Example {
+	examples := make([]Example, len(exs))
+	for i, ex := range exs {
+		name := "Example" + ex.Name
+
+		var position Position
+		if decl, ok := decls[name]; ok {
+			position = newPosition(decl.Pos(), decl.End(), fset)
+		}
+
+		examples[i] = Example{
+			name:      name,
+			suffix:    ex.Suffix,
+			symbol:    symbol,
+			comments:  ex.Doc,
+			body:      extractExampleBody(ex, fset),
+			output:    ex.Output,
+			hasOutput: ex.Output != "" || ex.EmptyOutput,
+			unordered: ex.Unordered,
+			position:  position,
+		}
+	}
+
+	return examples
+}
+
+// extractExampleBody extracts the source for the body of an example, without the surrounding braces
+// and indentation and without the output comment.
+func extractExampleBody(ex *doc.Example, fset *token.FileSet) string {
+	if ex.Code == nil || fset == nil {
+		return ""
+	}
+
+	sb := new(strings.Builder)
+	if err := format.Node(sb, fset, &printer.CommentedNode{Node: ex.Code, Comments: ex.Comments}); err != nil {
+		return ""
+	}
+
+	body := strings.TrimSpace(sb.String())
+	body = strings.TrimSuffix(strings.TrimPrefix(body, "{"), "}")
+	lines := strings.Split(strings.Trim(body, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, "\t")
+	}
+	body = strings.Join(lines, "\n")
+
+	if loc := exampleOutput.FindStringIndex(body); loc != nil {
+		body = body[:loc[0]]
+	}
+
+	return strings.TrimSpace(body)
+}
+
+// attachExamples adds the examples that go/doc associated with each declaration to the package's
+// functions, types, and methods, and collects every example in the package, sorted by name.
+func attachExamples(pkg *Package, docPkg *doc.Package, testFiles []*ast.File, fset *token.FileSet) {
+	decls := make(map[string]*ast.FuncDecl)
+	for _, f := range testFiles {
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil {
+				decls[fd.Name.Name] = fd
+			}
+		}
+	}
+
+	pkg.examples = newExamples(docPkg.Examples, "", decls, fset)
+	for i, f := range docPkg.Funcs {
+		pkg.functions[i].examples = newExamples(f.Examples, f.Name, decls, fset)
+		pkg.examples = append(pkg.examples, pkg.functions[i].examples...)
+	}
+	for i, t := range docPkg.Types {
+		pkg.types[i].examples = newExamples(t.Examples, t.Name, decls, fset)
+		pkg.examples = append(pkg.examples, pkg.types[i].examples...)
+		for j, f := range t.Funcs {
+			pkg.types[i].functions[j].examples = newExamples(f.Examples, f.Name, decls, fset)
+			pkg.examples = append(pkg.examples, pkg.types[i].functions[j].examples...)
+		}
+		for j, m := range t.Methods {
+			pkg.types[i].methods[j].examples = newExamples(m.Examples, t.Name+"."+m.Name, decls, fset)
+			pkg.examples = append(pkg.examples, pkg.types[i].methods[j].examples...)
+		}
+	}
+
+	sort.Slice(pkg.examples, func(i, j int) bool {
+		return pkg.examples[i].name < pkg.examples[j].name
+	})
+}
+
+// Name returns the example's full name, like "ExampleFile_Close_second".
+func (e Example) Name() string {
+	return e.name
+}
+
+// Suffix returns the suffix after the name of the symbol that the example documents, like "second"
+// for "ExampleFile_Close_second", or "" if there is no suffix.
+func (e Example) Suffix() string {
+	return e.suffix
+}
+
+// Symbol returns the declaration that the example documents, like "Open" for a function or type or
+// "File.Close" for a method. For an example of the whole package, this returns "".
+func (e Example) Symbol() string {
+	return e.symbol
+}
+
+// Comments returns the documentation for this example with pkg's formatting applied.
+func (e Example) Comments(width int) string {
+	return formatComments(e.comments, width)
+}
+
+// Body returns the source of the example's body, without the surrounding braces and the output
+// comment.
+func (e Example) Body() string {
+	return e.body
+}
+
+// Output returns the output that the example is expected to print.
+func (e Example) Output() string {
+	return e.output
+}
+
+// HasOutput reports whether or not the example has an output comment. Examples without one are
+// compiled but not run by go test.
+func (e Example) HasOutput() bool {
+	return e.hasOutput
+}
+
+// Unordered reports whether or not the lines of the example's output can be printed in any order.
+func (e Example) Unordered() bool {
+	return e.unordered
+}
+
+// Position returns the location of the example's declaration, including its body.
+func (e Example) Position() Position {
+	return e.position
+}
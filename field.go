@@ -0,0 +1,219 @@
+// This file contains the information and logic for the Field type.
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// Field holds information about a field in a struct type.
+type Field struct {
+	// Name of this field. For embedded fields, this is the name of the embedded type.
+	name string
+
+	// Name of this field's type, like "[]byte" or "*os.File".
+	typeName string
+
+	// Raw struct tag for this field, without the surrounding quotes.
+	tag string
+
+	// Parsed key/value pairs in the struct tag, in the order they appear.
+	tags []fieldTag
+
+	// Comments (documentation) above this field.
+	comments string
+
+	// Comment on the same line as this field.
+	lineComment string
+
+	// Whether or not this field is an embedded field.
+	embedded bool
+
+	// Location of this field's declaration.
+	position Position
+}
+
+// fieldTag is a single key/value pair in a struct tag.
+type fieldTag struct {
+	key   string
+	value string
+}
+
+// newFields extracts all fields for the given struct type.
+func newFields(st *ast.StructType, fset *token.FileSet) []Field {
+	if st == nil || st.Fields == nil {
+		return nil
+	}
+
+	fields := make([]Field, 0, len(st.Fields.List))
+	for _, f := range st.Fields.List {
+		// Read out the information that is shared by all fields declared together.
+		typeName := strings.TrimSpace(extractSource(f.Type, fset))
+
+		tag := ""
+		if f.Tag != nil {
+			if s, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = s
+			}
+		}
+
+		field := Field{
+			typeName:    typeName,
+			tag:         tag,
+			tags:        parseTag(tag),
+			comments:    f.Doc.Text(),
+			lineComment: strings.TrimSpace(f.Comment.Text()),
+		}
+
+		if len(f.Names) == 0 {
+			// Embedded field. The field's name is the name of the type without any pointer, package,
+			// or type arguments.
+			field.name = embeddedName(f.Type)
+			field.embedded = true
+			field.position = newPosition(f.Pos(), f.End(), fset)
+			fields = append(fields, field)
+		} else {
+			// Add a separate field for each name that was declared together.
+			for _, name := range f.Names {
+				field.name = name.Name
+				field.position = newPosition(name.Pos(), f.End(), fset)
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	return fields
+}
+
+// embeddedName returns the field name for an embedded field of type expr.
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(e.X)
+	case *ast.IndexListExpr:
+		return embeddedName(e.X)
+	default:
+		return ""
+	}
+}
+
+// parseTag splits a struct tag into its key/value pairs. It follows the conventional format
+// described in reflect's StructTag and stops at the first malformed pair.
+func parseTag(tag string) []fieldTag {
+	var tags []fieldTag
+	for tag != "" {
+		// Skip leading space.
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			break
+		}
+
+		// Scan to the colon. The key is a non-empty string of non-control, non-space, non-quote,
+		// non-colon characters.
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan the quoted string to find the value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			break
+		}
+		tag = tag[i+1:]
+
+		tags = append(tags, fieldTag{key: key, value: value})
+	}
+
+	return tags
+}
+
+// Name returns the field's name. For embedded fields, this is the name of the embedded type without
+// any pointer, package qualifier, or type arguments, like "Reader" for an embedded *bufio.Reader.
+func (f Field) Name() string {
+	return f.name
+}
+
+// Type returns the name of the field's type, like "[]byte" or "*os.File".
+func (f Field) Type() string {
+	return f.typeName
+}
+
+// Tag returns the field's raw struct tag without the surrounding quotes, or "" if the field does not
+// have a tag.
+func (f Field) Tag() string {
+	return f.tag
+}
+
+// TagKeys returns the keys in the field's struct tag in the order they appear, like ["json", "xml"]
+// for the tag `json:"name" xml:"name"`.
+func (f Field) TagKeys() []string {
+	keys := make([]string, len(f.tags))
+	for i, t := range f.tags {
+		keys[i] = t.key
+	}
+
+	return keys
+}
+
+// Tags returns a map of the key/value pairs in the field's struct tag, like {"json": "name,omitempty"}
+// for the tag `json:"name,omitempty"`.
+func (f Field) Tags() map[string]string {
+	m := make(map[string]string, len(f.tags))
+	for _, t := range f.tags {
+		if _, ok := m[t.key]; !ok {
+			m[t.key] = t.value
+		}
+	}
+
+	return m
+}
+
+// Comments returns the documentation above this field with pkg's formatting applied.
+func (f Field) Comments(width int) string {
+	return formatComments(f.comments, width)
+}
+
+// LineComment returns the comment on the same line as this field, or "" if there is none.
+func (f Field) LineComment() string {
+	return f.lineComment
+}
+
+// Position returns the location of the field's declaration, from the field's name to the end of its
+// tag (or type, if there is no tag).
+func (f Field) Position() Position {
+	return f.position
+}
+
+// Embedded reports whether or not this field is an embedded field.
+func (f Field) Embedded() bool {
+	return f.embedded
+}
+
+// Exported reports whether or not this field is exported.
+func (f Field) Exported() bool {
+	return ast.IsExported(f.name)
+}
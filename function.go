@@ -2,6 +2,7 @@
 package pkg
 
 import (
+	"go/ast"
 	"go/doc"
 	"go/token"
 )
@@ -14,28 +15,49 @@ type Function struct {
 	// Comments for this function.
 	comments string
 
+	// Type parameters, if this is a generic function.
+	typeParams []TypeParam
+
+	// Source of this function's signature, without the body.
+	source string
+
 	// Input parameters.
 	inputs []Parameter
 
 	// Output parameters.
 	outputs []Parameter
+
+	// Location of this function's declaration.
+	position Position
+
+	// Build constraint that this function is declared under, or "" if it is built everywhere.
+	constraint string
+
+	// Examples of this function from the package's test files.
+	examples []Example
 }
 
 // newFunction builds a new Function object based on go/doc's Func.
-func newFunction(f *doc.Func, fset *token.FileSet) Function {
+func newFunction(f *doc.Func, fset *token.FileSet, ti *typeInfo) Function {
 	if f == nil {
 		return Function{}
 	}
 
+	// Extract the type parameters.
+	typeParams := newTypeParams(f.Decl.Type.TypeParams, fset, ti)
+
 	// Extract the parameters.
 	in := newParameters(f.Decl.Type.Params, fset)
 	out := newParameters(f.Decl.Type.Results, fset)
 
 	return Function{
-		name:     f.Name,
-		comments: f.Doc,
-		inputs:   in,
-		outputs:  out,
+		name:       f.Name,
+		comments:   f.Doc,
+		typeParams: typeParams,
+		source:     extractFuncSource(f.Decl, fset),
+		inputs:     in,
+		outputs:    out,
+		position:   newPosition(f.Decl.Pos(), f.Decl.End(), fset),
 	}
 }
 
@@ -44,11 +66,41 @@ func (f Function) Name() string {
 	return f.name
 }
 
+// Exported reports whether or not this function is exported.
+func (f Function) Exported() bool {
+	return ast.IsExported(f.name)
+}
+
 // Comments returns the documentation for this function with pkg's formatting applied.
 func (f Function) Comments(width int) string {
 	return formatComments(f.comments, width)
 }
 
+// Position returns the location of the function's declaration, including its body.
+func (f Function) Position() Position {
+	return f.position
+}
+
+// Constraint returns the build constraint that the function is declared under, like
+// "linux && amd64", or "" if it is built everywhere. If the function is declared in more than one
+// file (like once for each operating system), the constraint is satisfied when any of those files
+// is built.
+func (f Function) Constraint() string {
+	return f.constraint
+}
+
+// Source returns the source of the function's signature without its body, like
+// "func Open(name string) (*File, error)".
+func (f Function) Source() string {
+	return f.source
+}
+
+// TypeParams returns a list of type parameters for this function if it is generic, or an empty list
+// otherwise.
+func (f Function) TypeParams() []TypeParam {
+	return append([]TypeParam{}, f.typeParams...)
+}
+
 // Inputs returns a list of input parameters sent to this function, or nil on invalid object. If
 // there are no input parameters, this returns a slice of size 0..
 func (f Function) Inputs() []Parameter {
@@ -60,3 +112,8 @@ func (f Function) Inputs() []Parameter {
 func (f Function) Outputs() []Parameter {
 	return append([]Parameter{}, f.outputs...)
 }
+
+// Examples returns a list of examples of this function from the package's test files.
+func (f Function) Examples() []Example {
+	return append([]Example{}, f.examples...)
+}
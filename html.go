@@ -0,0 +1,476 @@
+// This file contains the logic for rendering a package's documentation as HTML.
+package pkg
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc/comment"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"html"
+	"io"
+	"strings"
+)
+
+// HTMLOptions holds the options for rendering a package as HTML.
+type HTMLOptions struct {
+	// Sections to render. If 0, every section is rendered.
+	Sections Section
+
+	// PackageURL returns the URL of the documentation for the package at importPath. It is used to
+	// link to the packages that this package imports. If nil, links point to pkg.go.dev.
+	PackageURL func(importPath string) string
+}
+
+// htmlStyle is the stylesheet that is included in every page.
+const htmlStyle = `
+body { font-family: sans-serif; line-height: 1.5; margin: 0 auto; max-width: 60rem; padding: 1rem; color: #202224; }
+a { color: #007d9c; text-decoration: none; }
+a:hover { text-decoration: underline; }
+pre { background: #f8f8f8; border: 1px solid #dadce0; border-radius: 4px; overflow-x: auto; padding: 0.75rem; }
+summary { cursor: pointer; }
+summary h2 { display: inline; }
+ul.index { list-style: none; padding-left: 1rem; }
+ul.index ul { list-style: none; }
+section { margin-left: 1rem; }
+.keyword { color: #a626a4; }
+.comment { color: #6a737d; }
+.literal { color: #0a7e07; }
+`
+
+// htmlRenderer holds the state for rendering one package as HTML.
+type htmlRenderer struct {
+	// Package being rendered.
+	p Package
+
+	// Output being built.
+	sb strings.Builder
+
+	// Sections to render.
+	sections Section
+
+	// Function that returns the URL of an imported package's documentation.
+	packageURL func(string) string
+
+	// Names of the types declared in the package.
+	typeNames map[string]bool
+
+	// Import paths of the packages imported by the package, keyed by package name.
+	importNames map[string]string
+}
+
+// RenderHTML writes the documentation for p to w as a standalone HTML page, similar to the pages on
+// pkg.go.dev. The page can include the package's overview, an index, and the package's constants,
+// variables, functions, and types (with their constructors and methods), depending on which sections
+// are selected in opts. Each section can be collapsed, and all source is syntax-highlighted. Every use
+// of a type declared in the package links to the type's declaration, and every use of an imported
+// package links to that package's documentation.
+func RenderHTML(w io.Writer, p Package, opts HTMLOptions) error {
+	r := htmlRenderer{
+		p:           p,
+		sections:    opts.Sections,
+		packageURL:  opts.PackageURL,
+		typeNames:   make(map[string]bool, len(p.types)),
+		importNames: p.importNames(),
+	}
+	if r.sections == 0 {
+		r.sections = SectionAll
+	}
+	if r.packageURL == nil {
+		r.packageURL = defaultPackageURL
+	}
+	for _, t := range p.types {
+		r.typeNames[t.name] = true
+	}
+
+	r.sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	r.sb.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&r.sb, "<title>%s - Go Documentation</title>\n", html.EscapeString(p.name))
+	fmt.Fprintf(&r.sb, "<style>%s</style>\n</head>\n<body>\n<main>\n", htmlStyle)
+
+	if r.sections.Has(SectionOverview) {
+		r.renderOverview()
+	}
+	if r.sections.Has(SectionIndex) {
+		r.renderIndex()
+	}
+	if r.sections.Has(SectionConstants) {
+		r.renderConstants()
+	}
+	if r.sections.Has(SectionVariables) {
+		r.renderVariables()
+	}
+	if r.sections.Has(SectionFunctions) {
+		r.renderFunctions()
+	}
+	if r.sections.Has(SectionTypes) {
+		r.renderTypes()
+	}
+
+	r.sb.WriteString("</main>\n</body>\n</html>\n")
+
+	if _, err := io.WriteString(w, r.sb.String()); err != nil {
+		return fmt.Errorf("error writing html: %w", err)
+	}
+
+	return nil
+}
+
+// renderOverview renders the package's heading, import path, and overview comments.
+func (r *htmlRenderer) renderOverview() {
+	fmt.Fprintf(&r.sb, "<h1 id=\"pkg-overview\">Package %s</h1>\n", html.EscapeString(r.p.name))
+	if r.p.importPath != "" {
+		r.code(fmt.Sprintf("import %q", r.p.importPath))
+	}
+	r.comments(r.p.comments, 2)
+}
+
+// renderIndex renders the list of links to everything in the package.
+func (r *htmlRenderer) renderIndex() {
+	r.openSection("pkg-index", "Index")
+	r.sb.WriteString("<ul class=\"index\">\n")
+	if len(r.p.constantBlocks) > 0 {
+		r.sb.WriteString("<li><a href=\"#pkg-constants\">Constants</a></li>\n")
+	}
+	if len(r.p.variableBlocks) > 0 {
+		r.sb.WriteString("<li><a href=\"#pkg-variables\">Variables</a></li>\n")
+	}
+	for _, f := range r.p.functions {
+		r.indexEntry(f.name, f.source)
+		r.sb.WriteString("</li>\n")
+	}
+	for _, t := range r.p.types {
+		r.indexEntry(t.name, "type "+t.name)
+		if len(t.functions) > 0 || len(t.methods) > 0 {
+			r.sb.WriteString("\n<ul>\n")
+			for _, f := range t.functions {
+				r.indexEntry(f.name, f.source)
+				r.sb.WriteString("</li>\n")
+			}
+			for _, m := range t.methods {
+				r.indexEntry(t.name+"."+m.name, m.source)
+				r.sb.WriteString("</li>\n")
+			}
+			r.sb.WriteString("</ul>\n")
+		}
+		r.sb.WriteString("</li>\n")
+	}
+	r.sb.WriteString("</ul>\n")
+	r.closeSection()
+}
+
+// indexEntry opens an item in the index with a link to anchor. The caller must close the item.
+func (r *htmlRenderer) indexEntry(anchor string, source string) {
+	fmt.Fprintf(&r.sb, "<li><a href=\"#%s\">%s</a>", html.EscapeString(anchor), html.EscapeString(oneLine(source)))
+}
+
+// renderConstants renders every block of constants.
+func (r *htmlRenderer) renderConstants() {
+	if len(r.p.constantBlocks) == 0 {
+		return
+	}
+
+	r.openSection("pkg-constants", "Constants")
+	for _, cb := range r.p.constantBlocks {
+		for _, c := range cb.constants {
+			r.anchor(c.name)
+		}
+		r.code(cb.source)
+		r.comments(cb.comments, 3)
+	}
+	r.closeSection()
+}
+
+// renderVariables renders every block of variables.
+func (r *htmlRenderer) renderVariables() {
+	if len(r.p.variableBlocks) == 0 {
+		return
+	}
+
+	r.openSection("pkg-variables", "Variables")
+	for _, vb := range r.p.variableBlocks {
+		for _, v := range vb.variables {
+			r.anchor(v.name)
+		}
+		r.code(vb.source)
+		r.comments(vb.comments, 3)
+	}
+	r.closeSection()
+}
+
+// renderFunctions renders every function that is not grouped under a type.
+func (r *htmlRenderer) renderFunctions() {
+	if len(r.p.functions) == 0 {
+		return
+	}
+
+	r.openSection("pkg-functions", "Functions")
+	for _, f := range r.p.functions {
+		r.function(f, 3)
+	}
+	r.closeSection()
+}
+
+// renderTypes renders every type with its constructors and methods.
+func (r *htmlRenderer) renderTypes() {
+	if len(r.p.types) == 0 {
+		return
+	}
+
+	r.openSection("pkg-types", "Types")
+	for _, t := range r.p.types {
+		fmt.Fprintf(&r.sb, "<section id=\"%s\">\n", html.EscapeString(t.name))
+		fmt.Fprintf(&r.sb, "<h3>type %s</h3>\n", html.EscapeString(t.name))
+		r.code(t.source)
+		r.comments(t.comments, 4)
+
+		for _, f := range t.functions {
+			r.function(f, 4)
+		}
+		for _, m := range t.methods {
+			fmt.Fprintf(&r.sb, "<section id=\"%s.%s\">\n", html.EscapeString(t.name), html.EscapeString(m.name))
+			fmt.Fprintf(&r.sb, "<h4>func (%s) %s</h4>\n", html.EscapeString(m.receiver.String()), html.EscapeString(m.name))
+			r.code(m.source)
+			r.comments(m.comments, 5)
+			r.sb.WriteString("</section>\n")
+		}
+		r.sb.WriteString("</section>\n")
+	}
+	r.closeSection()
+}
+
+// function renders a single function with a heading at the given level.
+func (r *htmlRenderer) function(f Function, level int) {
+	fmt.Fprintf(&r.sb, "<section id=\"%s\">\n", html.EscapeString(f.name))
+	fmt.Fprintf(&r.sb, "<h%d>func %s</h%d>\n", level, html.EscapeString(f.name), level)
+	r.code(f.source)
+	r.comments(f.comments, level+1)
+	r.sb.WriteString("</section>\n")
+}
+
+// openSection opens a collapsible section with a heading.
+func (r *htmlRenderer) openSection(id string, heading string) {
+	fmt.Fprintf(&r.sb, "<details id=\"%s\" open>\n<summary><h2>%s</h2></summary>\n", id, heading)
+}
+
+// closeSection closes the section that was opened last.
+func (r *htmlRenderer) closeSection() {
+	r.sb.WriteString("</details>\n")
+}
+
+// anchor renders an anchor for an item that does not have its own heading, like a constant or
+// variable in a block.
+func (r *htmlRenderer) anchor(id string) {
+	fmt.Fprintf(&r.sb, "<span id=\"%s\"></span>\n", html.EscapeString(id))
+}
+
+// code renders syntax-highlighted source.
+func (r *htmlRenderer) code(source string) {
+	source = strings.TrimRight(source, "\n")
+	if source == "" {
+		return
+	}
+
+	fmt.Fprintf(&r.sb, "<pre><code>%s</code></pre>\n", r.highlight(source))
+}
+
+// comments renders documentation as HTML. Headings within the documentation start at the given level,
+// and links to other items point to their declarations.
+func (r *htmlRenderer) comments(text string, level int) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	if level > 6 {
+		level = 6
+	}
+
+	printer := comment.Printer{
+		HeadingLevel: level,
+		DocLinkURL: func(link *comment.DocLink) string {
+			return docLinkURL(link, r.packageURL)
+		},
+	}
+
+	r.sb.Write(printer.HTML(newCommentParser(r.p).Parse(text)))
+}
+
+// highlight returns source as escaped HTML with syntax highlighting. Keywords, comments, and literals
+// are wrapped in spans with the classes "keyword", "comment", and "literal". Names of types declared in
+// the package link to the type's declaration, and names of imported packages (and the names that are
+// selected from them) link to that package's documentation. Only names in type position are linked,
+// so parameters and fields that happen to share a name with a type or package are not.
+func (r *htmlRenderer) highlight(source string) string {
+	types := typeIdents(source)
+	src := []byte(source)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	sb := new(strings.Builder)
+	last := 0
+	pending := ""      // Import path of the package named by the last token, like "io" in "io.Reader".
+	selectedFrom := "" // Import path of the package that the next name is selected from.
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			// Automatically inserted semicolon.
+			continue
+		}
+
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		start := file.Offset(pos)
+		end := start + len(text)
+		if end > len(source) {
+			end = len(source)
+		}
+		text = source[start:end]
+
+		sb.WriteString(html.EscapeString(source[last:start]))
+		last = end
+
+		linkable := tok == token.IDENT && types[start]
+		switch {
+		case tok.IsKeyword():
+			fmt.Fprintf(sb, "<span class=\"keyword\">%s</span>", text)
+		case tok == token.COMMENT:
+			fmt.Fprintf(sb, "<span class=\"comment\">%s</span>", html.EscapeString(text))
+		case tok.IsLiteral() && tok != token.IDENT:
+			fmt.Fprintf(sb, "<span class=\"literal\">%s</span>", html.EscapeString(text))
+		case tok == token.IDENT && selectedFrom != "":
+			fmt.Fprintf(sb, "<a href=\"%s#%s\">%s</a>", html.EscapeString(r.packageURL(selectedFrom)), text, text)
+		case linkable && r.importNames[text] != "":
+			fmt.Fprintf(sb, "<a href=\"%s\">%s</a>", html.EscapeString(r.packageURL(r.importNames[text])), text)
+		case linkable && r.typeNames[text]:
+			fmt.Fprintf(sb, "<a href=\"#%s\">%s</a>", text, text)
+		default:
+			sb.WriteString(html.EscapeString(text))
+		}
+
+		// Keep track of the package when a name is selected from an imported package.
+		switch {
+		case linkable && r.importNames[text] != "":
+			pending, selectedFrom = r.importNames[text], ""
+		case tok == token.PERIOD && pending != "":
+			pending, selectedFrom = "", pending
+		default:
+			pending, selectedFrom = "", ""
+		}
+	}
+	sb.WriteString(html.EscapeString(source[last:]))
+
+	return sb.String()
+}
+
+// typeIdents returns the offsets in the declaration source of the names that are in type position,
+// like "Buffer" and "io" in "func Copy(b *Buffer, r io.Reader)". Names that are declared, like those
+// of parameters and fields, and names that are selected from something else, like "Reader" in
+// "io.Reader", are not included. If source cannot be parsed, no names are included.
+func typeIdents(source string) map[int]bool {
+	const prefix = "package p\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", prefix+source, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	offsets := make(map[int]bool)
+	add := func(ident *ast.Ident) {
+		offsets[fset.Position(ident.Pos()).Offset-len(prefix)] = true
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			fieldTypeIdents(d.Recv, add)
+			exprTypeIdents(d.Type, add)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					fieldTypeIdents(sp.TypeParams, add)
+					exprTypeIdents(sp.Type, add)
+				case *ast.ValueSpec:
+					exprTypeIdents(sp.Type, add)
+					for _, value := range sp.Values {
+						ast.Inspect(value, func(n ast.Node) bool {
+							if lit, ok := n.(*ast.CompositeLit); ok {
+								exprTypeIdents(lit.Type, add)
+							}
+							return true
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return offsets
+}
+
+// fieldTypeIdents calls add for the names in type position in the types of fields, but not for the
+// names of the fields themselves.
+func fieldTypeIdents(fields *ast.FieldList, add func(*ast.Ident)) {
+	if fields == nil {
+		return
+	}
+
+	for _, f := range fields.List {
+		exprTypeIdents(f.Type, add)
+	}
+}
+
+// exprTypeIdents calls add for the names in type position in the type expression expr. For a qualified
+// name like "io.Reader", only the package name is added.
+func exprTypeIdents(expr ast.Expr, add func(*ast.Ident)) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		add(e)
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok {
+			add(x)
+		}
+	case *ast.StarExpr:
+		exprTypeIdents(e.X, add)
+	case *ast.ParenExpr:
+		exprTypeIdents(e.X, add)
+	case *ast.UnaryExpr:
+		exprTypeIdents(e.X, add)
+	case *ast.BinaryExpr:
+		exprTypeIdents(e.X, add)
+		exprTypeIdents(e.Y, add)
+	case *ast.ArrayType:
+		exprTypeIdents(e.Elt, add)
+	case *ast.Ellipsis:
+		exprTypeIdents(e.Elt, add)
+	case *ast.MapType:
+		exprTypeIdents(e.Key, add)
+		exprTypeIdents(e.Value, add)
+	case *ast.ChanType:
+		exprTypeIdents(e.Value, add)
+	case *ast.IndexExpr:
+		exprTypeIdents(e.X, add)
+		exprTypeIdents(e.Index, add)
+	case *ast.IndexListExpr:
+		exprTypeIdents(e.X, add)
+		for _, index := range e.Indices {
+			exprTypeIdents(index, add)
+		}
+	case *ast.FuncType:
+		fieldTypeIdents(e.TypeParams, add)
+		fieldTypeIdents(e.Params, add)
+		fieldTypeIdents(e.Results, add)
+	case *ast.StructType:
+		fieldTypeIdents(e.Fields, add)
+	case *ast.InterfaceType:
+		fieldTypeIdents(e.Methods, add)
+	}
+}
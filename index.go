@@ -0,0 +1,450 @@
+// This file contains the logic for searching the declarations of many packages at once.
+package pkg
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// indexField is a part of a declaration that is searched.
+type indexField int
+
+const (
+	// fieldName is the declaration's name, like "ReadAll" or "Close" (for "File.Close").
+	fieldName indexField = iota
+
+	// fieldSignature is the declaration's source, like "func ReadAll(r Reader) ([]byte, error)".
+	fieldSignature
+
+	// fieldComments is the declaration's documentation.
+	fieldComments
+
+	// fieldPackage is the import path and name of the package that declares it.
+	fieldPackage
+)
+
+// fieldWeights is how much a match in each field counts towards a result's score.
+var fieldWeights = [...]float64{
+	fieldName:      4,
+	fieldSignature: 2,
+	fieldComments:  1,
+	fieldPackage:   0.5,
+}
+
+// Match qualities, for exact matches of a token and for those that are only close.
+const (
+	matchExact  = 1.0
+	matchPrefix = 0.7
+	matchFuzzy  = 0.5
+)
+
+// stopWords are common English words that are not indexed or searched for.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "if": true, "in": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "with": true,
+}
+
+// Index holds the declarations of many packages for searching. It is built once with NewIndex and is
+// safe for concurrent use.
+type Index struct {
+	// Declarations in the index.
+	entries []indexEntry
+
+	// Declarations that contain each token, keyed by token.
+	postings map[string][]posting
+
+	// Every token in the index, sorted.
+	tokens []string
+
+	// Method names of every type in the index, keyed by the type's import path and name, like
+	// "io.Reader" or "text/template.Template".
+	methods map[string]typeMethods
+
+	// Import paths of the packages in the index, keyed by package name and sorted (see indexPath).
+	pkgPaths map[string][]string
+}
+
+// indexEntry holds one declaration in an Index.
+type indexEntry struct {
+	// Import path of the package that declares the symbol.
+	importPath string
+
+	// Name of the package that declares the symbol.
+	pkgName string
+
+	// Declaration itself.
+	symbol Symbol
+
+	// Declaration's unqualified name in lower case, like "close" for "File.Close".
+	name string
+
+	// Signature of the declaration if it is a function or method, or nil otherwise.
+	signature *funcSignature
+}
+
+// posting records that a token appears in one field of one declaration.
+type posting struct {
+	// Index of the declaration in the index's entries.
+	entry int
+
+	// Field that the token appears in.
+	field indexField
+}
+
+// SearchResult holds a declaration that matched a search of an Index.
+type SearchResult struct {
+	// Import path of the package that declares the symbol.
+	importPath string
+
+	// Declaration that matched.
+	symbol Symbol
+
+	// How well the declaration matched. Higher is better.
+	score float64
+}
+
+// NewIndex builds an index of every declaration in pkgs: constants, variables, errors, functions,
+// types, methods, and fields. Each declaration is indexed by its name, its source, its documentation,
+// and its package's import path. Names are split into words at changes of case as well as at
+// punctuation, so "ReadAll" is found by "read", "all", and "readall", and "HTTPServer" by "http" and
+// "server".
+func NewIndex(pkgs ...Package) Index {
+	idx := Index{
+		postings: make(map[string][]posting),
+		methods:  make(map[string]typeMethods),
+		pkgPaths: make(map[string][]string),
+	}
+
+	pkgNames := make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		if _, ok := pkgNames[indexPath(p)]; !ok {
+			pkgNames[indexPath(p)] = p.name
+			idx.pkgPaths[p.name] = append(idx.pkgPaths[p.name], indexPath(p))
+		}
+	}
+	for _, paths := range idx.pkgPaths {
+		sort.Strings(paths)
+	}
+
+	for _, p := range pkgs {
+		first := len(idx.entries)
+		pkgTokens := tokenize(p.importPath + " " + p.name)
+		for _, s := range p.allSymbols() {
+			name := s.name
+			if s.typeName != "" {
+				name = strings.TrimPrefix(name, s.typeName+".")
+			}
+
+			i := len(idx.entries)
+			idx.entries = append(idx.entries, indexEntry{
+				importPath: p.importPath,
+				pkgName:    p.name,
+				symbol:     s,
+				name:       strings.ToLower(name),
+			})
+
+			fields := [...][]string{
+				fieldName:      tokenize(name),
+				fieldSignature: tokenize(declSource(s)),
+				fieldComments:  tokenize(s.comments),
+				fieldPackage:   pkgTokens,
+			}
+			for field, tokens := range fields {
+				for _, token := range tokens {
+					idx.postings[token] = append(idx.postings[token], posting{entry: i, field: indexField(field)})
+				}
+			}
+		}
+
+		for _, t := range p.types {
+			idx.methods[indexPath(p)+"."+t.name] = newTypeMethods(t)
+		}
+
+		// Parse the signatures once here instead of for every search.
+		paths := qualifierPaths(p, pkgNames)
+		for i := first; i < len(idx.entries); i++ {
+			if sig, ok := entrySignature(idx.entries[i], paths); ok {
+				idx.entries[i].signature = &sig
+			}
+		}
+	}
+
+	idx.tokens = make([]string, 0, len(idx.postings))
+	for token := range idx.postings {
+		idx.tokens = append(idx.tokens, token)
+	}
+	sort.Strings(idx.tokens)
+
+	return idx
+}
+
+// qualifierPaths returns the import paths of p and of the packages that it imports, keyed by the
+// package names that qualify their types in p's signatures. The names of packages that are not in the
+// index are assumed the same way as for links in documentation (see importName).
+func qualifierPaths(p Package, pkgNames map[string]string) map[string]string {
+	paths := map[string]string{p.name: indexPath(p)}
+	for _, importPath := range p.imports {
+		name, ok := pkgNames[importPath]
+		if !ok {
+			name = importName(importPath)
+		}
+		// The package's own name takes precedence over the imports with the same name.
+		if _, ok := paths[name]; !ok {
+			paths[name] = importPath
+		}
+	}
+
+	return paths
+}
+
+// indexPath returns the path that stands for p in the index's method sets. This is p's import path, or
+// its name if it has none, like a package created with NewFromSources.
+func indexPath(p Package) string {
+	if p.importPath == "" {
+		return p.name
+	}
+
+	return p.importPath
+}
+
+// declSource returns the source of the declaration that is indexed for s. This is the symbol's source,
+// except for constants and variables, whose source is their whole block. For those, only the parts
+// of the block that declare the symbol are used, like "const Pi float64 = 3.14".
+func declSource(s Symbol) string {
+	switch s.kind {
+	case KindConstant:
+		return "const " + s.constant.name + " " + s.constant.typeName + " = " + s.constant.Value()
+	case KindVariable, KindError:
+		return "var " + s.variable.name + " " + s.variable.typeName + " = " + s.variable.initializer
+	default:
+		return s.source
+	}
+}
+
+// Len returns the number of declarations in the index.
+func (idx Index) Len() int {
+	return len(idx.entries)
+}
+
+// Search returns the declarations that best match query, with the best match first. query is a list
+// of words, like "io.Reader []byte" or "parse duration". A word matches a declaration if it appears in
+// the declaration's name, source, documentation, or import path, and words can also match the
+// beginning of a longer word or be misspelled by one letter, for a lower score. Matches in names count
+// the most, and matches in documentation and import paths count the least. Declarations that match
+// only some of the words are included after those that match all of them. At most limit results are
+// returned, or all of them if limit is 0 or less.
+func (idx Index) Search(query string, limit int) []SearchResult {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	total := make(map[int]float64)
+	matched := make(map[int]int)
+	for _, term := range terms {
+		// Score each declaration by the best match of this term in any of its fields. Every token that
+		// the term matches shares the same rarity, so that a close match never outranks an exact one.
+		best := make(map[int]float64)
+		for token, quality := range idx.matchTokens(term) {
+			for _, p := range idx.postings[token] {
+				if score := quality * fieldWeights[p.field]; score > best[p.entry] {
+					best[p.entry] = score
+				}
+			}
+		}
+		if len(best) == 0 {
+			continue
+		}
+		idf := math.Log(1 + float64(len(idx.entries))/float64(len(best)))
+		for entry, score := range best {
+			total[entry] += score * idf
+			matched[entry]++
+		}
+	}
+
+	results := make([]SearchResult, 0, len(total))
+	for entry, score := range total {
+		// Declarations that match only some of the terms are ranked much lower, and a name that is
+		// exactly the query ranks higher than one that only contains it.
+		coverage := float64(matched[entry]) / float64(len(terms))
+		score *= coverage * coverage
+		if len(terms) == 1 && idx.entries[entry].name == terms[0] {
+			score *= 2
+		}
+
+		results = append(results, SearchResult{
+			importPath: idx.entries[entry].importPath,
+			symbol:     idx.entries[entry].symbol,
+			score:      score,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch {
+		case a.score != b.score:
+			return a.score > b.score
+		case a.importPath != b.importPath:
+			return a.importPath < b.importPath
+		default:
+			return a.symbol.name < b.symbol.name
+		}
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// matchTokens returns the tokens in the index that match term and the quality of each match. Terms of
+// at least three letters also match longer tokens that begin with them, and terms of at least four
+// letters also match tokens that are one edit away.
+func (idx Index) matchTokens(term string) map[string]float64 {
+	matches := make(map[string]float64)
+	if _, ok := idx.postings[term]; ok {
+		matches[term] = matchExact
+	}
+
+	if utf8.RuneCountInString(term) >= 3 {
+		for i := sort.SearchStrings(idx.tokens, term); i < len(idx.tokens) && strings.HasPrefix(idx.tokens[i], term); i++ {
+			if _, ok := matches[idx.tokens[i]]; !ok {
+				matches[idx.tokens[i]] = matchPrefix
+			}
+		}
+	}
+
+	if utf8.RuneCountInString(term) >= 4 {
+		for _, token := range idx.tokens {
+			if _, ok := matches[token]; !ok && withinOneEdit(term, token) {
+				matches[token] = matchFuzzy
+			}
+		}
+	}
+
+	return matches
+}
+
+// tokenize splits text into the lower-case tokens that are indexed. Each identifier is a token, and so
+// is each of its words if it has more than one, like "readall", "read", and "all" for "ReadAll". Stop
+// words and single letters are left out, and each token is only returned once.
+func tokenize(text string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	add := func(token string) {
+		token = strings.ToLower(token)
+		if utf8.RuneCountInString(token) < 2 || stopWords[token] || seen[token] {
+			return
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+
+	for _, ident := range strings.FieldsFunc(text, isNotIdentRune) {
+		add(ident)
+		if words := splitWords(ident); len(words) > 1 {
+			for _, word := range words {
+				add(word)
+			}
+		}
+	}
+
+	return tokens
+}
+
+// queryTerms splits a query into the lower-case terms that are searched for. Unlike tokenize, each
+// identifier in the query is kept whole.
+func queryTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, ident := range strings.FieldsFunc(query, isNotIdentRune) {
+		term := strings.ToLower(ident)
+		if utf8.RuneCountInString(term) < 2 || stopWords[term] || seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+
+	return terms
+}
+
+// isNotIdentRune reports whether or not r cannot be part of a Go identifier.
+func isNotIdentRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+// splitWords splits an identifier into its words at underscores and changes of case, like "HTTP" and
+// "Server" for "HTTPServer" or "parse" and "Int64" for "parseInt64". Digits stay with the word before
+// them.
+func splitWords(ident string) []string {
+	var words []string
+	for _, part := range strings.Split(ident, "_") {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			next := rune(0)
+			if i+1 < len(runes) {
+				next = runes[i+1]
+			}
+
+			// A word begins at an upper-case letter after a lower-case letter or digit, or at the last
+			// upper-case letter of an acronym that is followed by a lower-case letter.
+			if unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && unicode.IsLower(next))) {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			words = append(words, string(runes[start:]))
+		}
+	}
+
+	return words
+}
+
+// withinOneEdit reports whether or not a can be changed into b by inserting, removing, or replacing a
+// single letter. Strings that are equal are not within one edit.
+func withinOneEdit(a string, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) > len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(rb)-len(ra) > 1 {
+		return false
+	}
+
+	// Skip the common prefix and suffix. What is left must be at most one letter in each string.
+	i := 0
+	for i < len(ra) && ra[i] == rb[i] {
+		i++
+	}
+	j := 0
+	for j < len(ra)-i && ra[len(ra)-1-j] == rb[len(rb)-1-j] {
+		j++
+	}
+
+	return len(rb)-i-j == 1 && len(ra)-i-j <= 1
+}
+
+// ImportPath returns the import path of the package that declares the symbol.
+func (r SearchResult) ImportPath() string {
+	return r.importPath
+}
+
+// Symbol returns the declaration that matched.
+func (r SearchResult) Symbol() Symbol {
+	return r.symbol
+}
+
+// Score returns how well the declaration matched the query. Higher is better. Scores can only be
+// compared between the results of the same search.
+func (r SearchResult) Score() float64 {
+	return r.score
+}
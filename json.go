@@ -0,0 +1,867 @@
+// This file contains the logic for encoding and decoding the package model as JSON.
+package pkg
+
+import (
+	_ "embed" // Needed for the JSON schema.
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// JSONVersion is the version of the JSON format produced by Package's MarshalJSON. It is increased
+// whenever the format changes in a way that older readers cannot handle.
+const JSONVersion = 1
+
+// ErrUnsupportedVersion is returned when decoding JSON that was produced by an unsupported version of
+// the format.
+var ErrUnsupportedVersion = fmt.Errorf("unsupported JSON version")
+
+// jsonSchema is the JSON Schema that describes the output of Package's MarshalJSON.
+//
+//go:embed schema.json
+var jsonSchema []byte
+
+// JSONSchema returns the JSON Schema (draft 2020-12) that describes the JSON format of a Package.
+func JSONSchema() []byte {
+	return append([]byte{}, jsonSchema...)
+}
+
+// jsonPackage is the JSON representation of a Package.
+type jsonPackage struct {
+	Version        int               `json:"version"`
+	Name           string            `json:"name"`
+	ImportPath     string            `json:"importPath,omitempty"`
+	Comments       string            `json:"comments,omitempty"`
+	ModulePath     string            `json:"modulePath,omitempty"`
+	ModuleVersion  string            `json:"moduleVersion,omitempty"`
+	ModuleRoot     string            `json:"moduleRoot,omitempty"`
+	Dir            string            `json:"dir,omitempty"`
+	Files          []string          `json:"files,omitempty"`
+	Constraints    map[string]string `json:"constraints,omitempty"`
+	TestFiles      []string          `json:"testFiles,omitempty"`
+	Subdirectories []string          `json:"subdirectories,omitempty"`
+	Imports        []string          `json:"imports,omitempty"`
+	TestImports    []string          `json:"testImports,omitempty"`
+	ConstantBlocks []ConstantBlock   `json:"constantBlocks,omitempty"`
+	VariableBlocks []VariableBlock   `json:"variableBlocks,omitempty"`
+	Functions      []Function        `json:"functions,omitempty"`
+	Types          []Type            `json:"types,omitempty"`
+	Tests          []TestFunction    `json:"tests,omitempty"`
+	Benchmarks     []TestFunction    `json:"benchmarks,omitempty"`
+	FuzzTargets    []TestFunction    `json:"fuzzTargets,omitempty"`
+	Examples       []Example         `json:"examples,omitempty"`
+}
+
+// jsonConstantBlock is the JSON representation of a ConstantBlock.
+type jsonConstantBlock struct {
+	Type       string     `json:"type,omitempty"`
+	Comments   string     `json:"comments,omitempty"`
+	Source     string     `json:"source,omitempty"`
+	Constants  []Constant `json:"constants,omitempty"`
+	Position   *Position  `json:"position,omitempty"`
+	Constraint string     `json:"constraint,omitempty"`
+}
+
+// jsonConstant is the JSON representation of a Constant.
+type jsonConstant struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type,omitempty"`
+	Value      *jsonConstValue `json:"value,omitempty"`
+	Comments   string          `json:"comments,omitempty"`
+	Position   *Position       `json:"position,omitempty"`
+	Constraint string          `json:"constraint,omitempty"`
+}
+
+// jsonConstValue is the JSON representation of a go/constant Value.
+type jsonConstValue struct {
+	Kind  string `json:"kind"`
+	Exact string `json:"exact"`
+}
+
+// jsonVariableBlock is the JSON representation of a VariableBlock.
+type jsonVariableBlock struct {
+	Type       string     `json:"type,omitempty"`
+	Comments   string     `json:"comments,omitempty"`
+	Source     string     `json:"source,omitempty"`
+	Variables  []Variable `json:"variables,omitempty"`
+	Errors     []Error    `json:"errors,omitempty"`
+	Position   *Position  `json:"position,omitempty"`
+	Constraint string     `json:"constraint,omitempty"`
+}
+
+// jsonVariable is the JSON representation of a Variable.
+type jsonVariable struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type,omitempty"`
+	Initializer string    `json:"initializer,omitempty"`
+	Comments    string    `json:"comments,omitempty"`
+	Position    *Position `json:"position,omitempty"`
+	Constraint  string    `json:"constraint,omitempty"`
+}
+
+// jsonError is the JSON representation of an Error.
+type jsonError struct {
+	Name       string    `json:"name"`
+	Message    string    `json:"message,omitempty"`
+	Position   *Position `json:"position,omitempty"`
+	Constraint string    `json:"constraint,omitempty"`
+}
+
+// jsonFunction is the JSON representation of a Function.
+type jsonFunction struct {
+	Name       string      `json:"name"`
+	Comments   string      `json:"comments,omitempty"`
+	TypeParams []TypeParam `json:"typeParams,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	Inputs     []Parameter `json:"inputs,omitempty"`
+	Outputs    []Parameter `json:"outputs,omitempty"`
+	Position   *Position   `json:"position,omitempty"`
+	Constraint string      `json:"constraint,omitempty"`
+	Examples   []Example   `json:"examples,omitempty"`
+}
+
+// jsonMethod is the JSON representation of a Method.
+type jsonMethod struct {
+	Name       string      `json:"name"`
+	Comments   string      `json:"comments,omitempty"`
+	Receiver   Parameter   `json:"receiver"`
+	TypeParams []TypeParam `json:"typeParams,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	Inputs     []Parameter `json:"inputs,omitempty"`
+	Outputs    []Parameter `json:"outputs,omitempty"`
+	Position   *Position   `json:"position,omitempty"`
+	Constraint string      `json:"constraint,omitempty"`
+	Examples   []Example   `json:"examples,omitempty"`
+}
+
+// jsonParameter is the JSON representation of a Parameter.
+type jsonParameter struct {
+	Name     string    `json:"name,omitempty"`
+	Type     string    `json:"type"`
+	Position *Position `json:"position,omitempty"`
+}
+
+// jsonType is the JSON representation of a Type.
+type jsonType struct {
+	Name               string      `json:"name"`
+	Comments           string      `json:"comments,omitempty"`
+	Type               string      `json:"type"`
+	TypeParams         []TypeParam `json:"typeParams,omitempty"`
+	Source             string      `json:"source,omitempty"`
+	Fields             []Field     `json:"fields,omitempty"`
+	InterfaceMethods   []Method    `json:"interfaceMethods,omitempty"`
+	EmbeddedInterfaces []string    `json:"embeddedInterfaces,omitempty"`
+	InterfaceMethodSet []Method    `json:"interfaceMethodSet,omitempty"`
+	UnexportedMethods  bool        `json:"unexportedMethods,omitempty"`
+	Functions          []Function  `json:"functions,omitempty"`
+	Methods            []Method    `json:"methods,omitempty"`
+	Position           *Position   `json:"position,omitempty"`
+	Constraint         string      `json:"constraint,omitempty"`
+	Examples           []Example   `json:"examples,omitempty"`
+}
+
+// jsonField is the JSON representation of a Field. The parsed struct tag is not stored because it
+// can be recreated from the raw tag.
+type jsonField struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Tag         string    `json:"tag,omitempty"`
+	Comments    string    `json:"comments,omitempty"`
+	LineComment string    `json:"lineComment,omitempty"`
+	Embedded    bool      `json:"embedded,omitempty"`
+	Position    *Position `json:"position,omitempty"`
+}
+
+// jsonTypeParam is the JSON representation of a TypeParam.
+type jsonTypeParam struct {
+	Name       string     `json:"name"`
+	Constraint string     `json:"constraint"`
+	Terms      []TypeTerm `json:"terms,omitempty"`
+}
+
+// jsonTypeTerm is the JSON representation of a TypeTerm.
+type jsonTypeTerm struct {
+	Type  string `json:"type"`
+	Tilde bool   `json:"tilde,omitempty"`
+}
+
+// jsonTestFunction is the JSON representation of a TestFunction.
+type jsonTestFunction struct {
+	Name     string    `json:"name"`
+	Comments string    `json:"comments,omitempty"`
+	Position *Position `json:"position,omitempty"`
+}
+
+// jsonExample is the JSON representation of an Example.
+type jsonExample struct {
+	Name      string    `json:"name"`
+	Suffix    string    `json:"suffix,omitempty"`
+	Symbol    string    `json:"symbol,omitempty"`
+	Comments  string    `json:"comments,omitempty"`
+	Body      string    `json:"body,omitempty"`
+	Output    string    `json:"output,omitempty"`
+	HasOutput bool      `json:"hasOutput,omitempty"`
+	Unordered bool      `json:"unordered,omitempty"`
+	Position  *Position `json:"position,omitempty"`
+}
+
+// jsonPosition is the JSON representation of a Position.
+type jsonPosition struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+}
+
+// positionPtr returns a pointer to p if it is valid, or nil otherwise so that unknown positions are
+// left out of the JSON.
+func positionPtr(p Position) *Position {
+	if !p.IsValid() {
+		return nil
+	}
+
+	return &p
+}
+
+// positionVal returns the position pointed to by p, or the zero position if p is nil.
+func positionVal(p *Position) Position {
+	if p == nil {
+		return Position{}
+	}
+
+	return *p
+}
+
+// MarshalJSON encodes the package and everything in it as JSON. The output includes the version of the
+// format (see JSONVersion) and is described by the schema returned by JSONSchema.
+func (p Package) MarshalJSON() ([]byte, error) {
+	return marshal(jsonPackage{
+		Version:        JSONVersion,
+		Name:           p.name,
+		ImportPath:     p.importPath,
+		Comments:       p.comments,
+		ModulePath:     p.modulePath,
+		ModuleVersion:  p.moduleVersion,
+		ModuleRoot:     p.moduleRoot,
+		Dir:            p.dir,
+		Files:          p.files,
+		Constraints:    p.constraints,
+		TestFiles:      p.testFiles,
+		Subdirectories: p.subdirectories,
+		Imports:        p.imports,
+		TestImports:    p.testImports,
+		ConstantBlocks: p.constantBlocks,
+		VariableBlocks: p.variableBlocks,
+		Functions:      p.functions,
+		Types:          p.types,
+		Tests:          p.tests,
+		Benchmarks:     p.benchmarks,
+		FuzzTargets:    p.fuzzTargets,
+		Examples:       p.examples,
+	})
+}
+
+// UnmarshalJSON decodes a package that was encoded with Package's MarshalJSON. It returns
+// ErrUnsupportedVersion if the JSON was produced by a newer version of the format.
+func (p *Package) UnmarshalJSON(data []byte) error {
+	var j jsonPackage
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	if j.Version < 1 || j.Version > JSONVersion {
+		return fmt.Errorf("%w: %v", ErrUnsupportedVersion, j.Version)
+	}
+
+	*p = Package{
+		name:           j.Name,
+		importPath:     j.ImportPath,
+		comments:       j.Comments,
+		modulePath:     j.ModulePath,
+		moduleVersion:  j.ModuleVersion,
+		moduleRoot:     j.ModuleRoot,
+		dir:            j.Dir,
+		files:          j.Files,
+		constraints:    j.Constraints,
+		testFiles:      j.TestFiles,
+		subdirectories: j.Subdirectories,
+		imports:        j.Imports,
+		testImports:    j.TestImports,
+		constantBlocks: j.ConstantBlocks,
+		variableBlocks: j.VariableBlocks,
+		functions:      j.Functions,
+		types:          j.Types,
+		tests:          j.Tests,
+		benchmarks:     j.Benchmarks,
+		fuzzTargets:    j.FuzzTargets,
+		examples:       j.Examples,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the block of constants as JSON.
+func (cb ConstantBlock) MarshalJSON() ([]byte, error) {
+	return marshal(jsonConstantBlock{
+		Type:       cb.typeName,
+		Comments:   cb.comments,
+		Source:     cb.source,
+		Constants:  cb.constants,
+		Position:   positionPtr(cb.position),
+		Constraint: cb.constraint,
+	})
+}
+
+// UnmarshalJSON decodes a block of constants that was encoded with ConstantBlock's MarshalJSON.
+func (cb *ConstantBlock) UnmarshalJSON(data []byte) error {
+	var j jsonConstantBlock
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*cb = ConstantBlock{
+		typeName:   j.Type,
+		comments:   j.Comments,
+		source:     j.Source,
+		constants:  j.Constants,
+		position:   positionVal(j.Position),
+		constraint: j.Constraint,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the constant as JSON. The value is stored as its kind and exact representation
+// so that it can be restored without any loss of precision.
+func (c Constant) MarshalJSON() ([]byte, error) {
+	j := jsonConstant{
+		Name:       c.name,
+		Type:       c.typeName,
+		Comments:   c.comments,
+		Position:   positionPtr(c.position),
+		Constraint: c.constraint,
+	}
+	if c.value != nil && c.value.Kind() != constant.Unknown {
+		j.Value = &jsonConstValue{
+			Kind:  strings.ToLower(c.value.Kind().String()),
+			Exact: c.value.ExactString(),
+		}
+	}
+
+	return marshal(j)
+}
+
+// UnmarshalJSON decodes a constant that was encoded with Constant's MarshalJSON.
+func (c *Constant) UnmarshalJSON(data []byte) error {
+	var j jsonConstant
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	var value constant.Value
+	if j.Value != nil {
+		v, err := decodeConstValue(j.Value.Kind, j.Value.Exact)
+		if err != nil {
+			return fmt.Errorf("invalid value for constant %s: %w", j.Name, err)
+		}
+		value = v
+	}
+
+	*c = Constant{
+		name:       j.Name,
+		typeName:   j.Type,
+		value:      value,
+		comments:   j.Comments,
+		position:   positionVal(j.Position),
+		constraint: j.Constraint,
+	}
+
+	return nil
+}
+
+// decodeConstValue rebuilds a go/constant Value from its kind and exact string representation.
+func decodeConstValue(kind string, exact string) (constant.Value, error) {
+	var value constant.Value
+	switch kind {
+	case "bool":
+		b, err := strconv.ParseBool(exact)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool %s: %w", exact, err)
+		}
+		value = constant.MakeBool(b)
+	case "string":
+		s, err := strconv.Unquote(exact)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s: %w", exact, err)
+		}
+		value = constant.MakeString(s)
+	case "int", "float":
+		value = decodeNumber(exact)
+	case "complex":
+		// Complex values have the form "(re + imi)".
+		re, im, ok := strings.Cut(strings.Trim(exact, "()"), " + ")
+		if !ok || !strings.HasSuffix(im, "i") {
+			return nil, fmt.Errorf("invalid complex number %s", exact)
+		}
+		value = constant.BinaryOp(decodeNumber(re), token.ADD, constant.MakeImag(decodeNumber(strings.TrimSuffix(im, "i"))))
+	default:
+		return nil, fmt.Errorf("unknown kind %s", kind)
+	}
+
+	if value.Kind() == constant.Unknown {
+		return nil, fmt.Errorf("invalid %s %s", kind, exact)
+	}
+
+	return value, nil
+}
+
+// decodeNumber rebuilds a numeric go/constant Value from its exact string representation, which is
+// either an integer, a fraction ("1/3"), or a floating-point literal.
+func decodeNumber(exact string) constant.Value {
+	if num, denom, ok := strings.Cut(exact, "/"); ok {
+		return constant.BinaryOp(decodeNumber(num), token.QUO, decodeNumber(denom))
+	}
+
+	if v := constant.MakeFromLiteral(exact, token.INT, 0); v.Kind() != constant.Unknown {
+		return v
+	}
+
+	return constant.MakeFromLiteral(exact, token.FLOAT, 0)
+}
+
+// MarshalJSON encodes the block of variables as JSON.
+func (vb VariableBlock) MarshalJSON() ([]byte, error) {
+	return marshal(jsonVariableBlock{
+		Type:       vb.typeName,
+		Comments:   vb.comments,
+		Source:     vb.source,
+		Variables:  vb.variables,
+		Errors:     vb.errors,
+		Position:   positionPtr(vb.position),
+		Constraint: vb.constraint,
+	})
+}
+
+// UnmarshalJSON decodes a block of variables that was encoded with VariableBlock's MarshalJSON.
+func (vb *VariableBlock) UnmarshalJSON(data []byte) error {
+	var j jsonVariableBlock
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*vb = VariableBlock{
+		typeName:   j.Type,
+		comments:   j.Comments,
+		source:     j.Source,
+		variables:  j.Variables,
+		errors:     j.Errors,
+		position:   positionVal(j.Position),
+		constraint: j.Constraint,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the variable as JSON.
+func (v Variable) MarshalJSON() ([]byte, error) {
+	return marshal(jsonVariable{
+		Name:        v.name,
+		Type:        v.typeName,
+		Initializer: v.initializer,
+		Comments:    v.comments,
+		Position:    positionPtr(v.position),
+		Constraint:  v.constraint,
+	})
+}
+
+// UnmarshalJSON decodes a variable that was encoded with Variable's MarshalJSON.
+func (v *Variable) UnmarshalJSON(data []byte) error {
+	var j jsonVariable
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*v = Variable{
+		name:        j.Name,
+		typeName:    j.Type,
+		initializer: j.Initializer,
+		comments:    j.Comments,
+		position:    positionVal(j.Position),
+		constraint:  j.Constraint,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the error as JSON.
+func (e Error) MarshalJSON() ([]byte, error) {
+	return marshal(jsonError{
+		Name:       e.name,
+		Message:    e.message,
+		Position:   positionPtr(e.position),
+		Constraint: e.constraint,
+	})
+}
+
+// UnmarshalJSON decodes an error that was encoded with Error's MarshalJSON.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var j jsonError
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*e = Error{
+		name:       j.Name,
+		message:    j.Message,
+		position:   positionVal(j.Position),
+		constraint: j.Constraint,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the function as JSON.
+func (f Function) MarshalJSON() ([]byte, error) {
+	return marshal(jsonFunction{
+		Name:       f.name,
+		Comments:   f.comments,
+		TypeParams: f.typeParams,
+		Source:     f.source,
+		Inputs:     f.inputs,
+		Outputs:    f.outputs,
+		Position:   positionPtr(f.position),
+		Constraint: f.constraint,
+		Examples:   f.examples,
+	})
+}
+
+// UnmarshalJSON decodes a function that was encoded with Function's MarshalJSON.
+func (f *Function) UnmarshalJSON(data []byte) error {
+	var j jsonFunction
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*f = Function{
+		name:       j.Name,
+		comments:   j.Comments,
+		typeParams: j.TypeParams,
+		source:     j.Source,
+		inputs:     j.Inputs,
+		outputs:    j.Outputs,
+		position:   positionVal(j.Position),
+		constraint: j.Constraint,
+		examples:   j.Examples,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the method as JSON.
+func (m Method) MarshalJSON() ([]byte, error) {
+	return marshal(jsonMethod{
+		Name:       m.name,
+		Comments:   m.comments,
+		Receiver:   m.receiver,
+		TypeParams: m.typeParams,
+		Source:     m.source,
+		Inputs:     m.inputs,
+		Outputs:    m.outputs,
+		Position:   positionPtr(m.position),
+		Constraint: m.constraint,
+		Examples:   m.examples,
+	})
+}
+
+// UnmarshalJSON decodes a method that was encoded with Method's MarshalJSON.
+func (m *Method) UnmarshalJSON(data []byte) error {
+	var j jsonMethod
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*m = Method{
+		name:       j.Name,
+		comments:   j.Comments,
+		receiver:   j.Receiver,
+		typeParams: j.TypeParams,
+		source:     j.Source,
+		inputs:     j.Inputs,
+		outputs:    j.Outputs,
+		position:   positionVal(j.Position),
+		constraint: j.Constraint,
+		examples:   j.Examples,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the parameter as JSON.
+func (p Parameter) MarshalJSON() ([]byte, error) {
+	return marshal(jsonParameter{
+		Name:     p.name,
+		Type:     p.typeName,
+		Position: positionPtr(p.position),
+	})
+}
+
+// UnmarshalJSON decodes a parameter that was encoded with Parameter's MarshalJSON.
+func (p *Parameter) UnmarshalJSON(data []byte) error {
+	var j jsonParameter
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*p = Parameter{
+		name:     j.Name,
+		typeName: j.Type,
+		position: positionVal(j.Position),
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the type as JSON.
+func (t Type) MarshalJSON() ([]byte, error) {
+	return marshal(jsonType{
+		Name:               t.name,
+		Comments:           t.comments,
+		Type:               t.typeName,
+		TypeParams:         t.typeParams,
+		Source:             t.source,
+		Fields:             t.fields,
+		InterfaceMethods:   t.interfaceMethods,
+		EmbeddedInterfaces: t.embeddedInterfaces,
+		InterfaceMethodSet: t.methodSet,
+		UnexportedMethods:  t.unexportedMethods,
+		Functions:          t.functions,
+		Methods:            t.methods,
+		Position:           positionPtr(t.position),
+		Constraint:         t.constraint,
+		Examples:           t.examples,
+	})
+}
+
+// UnmarshalJSON decodes a type that was encoded with Type's MarshalJSON.
+func (t *Type) UnmarshalJSON(data []byte) error {
+	var j jsonType
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*t = Type{
+		name:               j.Name,
+		comments:           j.Comments,
+		typeName:           j.Type,
+		typeParams:         j.TypeParams,
+		source:             j.Source,
+		fields:             j.Fields,
+		interfaceMethods:   j.InterfaceMethods,
+		embeddedInterfaces: j.EmbeddedInterfaces,
+		methodSet:          j.InterfaceMethodSet,
+		unexportedMethods:  j.UnexportedMethods,
+		functions:          j.Functions,
+		methods:            j.Methods,
+		position:           positionVal(j.Position),
+		constraint:         j.Constraint,
+		examples:           j.Examples,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the field as JSON.
+func (f Field) MarshalJSON() ([]byte, error) {
+	return marshal(jsonField{
+		Name:        f.name,
+		Type:        f.typeName,
+		Tag:         f.tag,
+		Comments:    f.comments,
+		LineComment: f.lineComment,
+		Embedded:    f.embedded,
+		Position:    positionPtr(f.position),
+	})
+}
+
+// UnmarshalJSON decodes a field that was encoded with Field's MarshalJSON.
+func (f *Field) UnmarshalJSON(data []byte) error {
+	var j jsonField
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*f = Field{
+		name:        j.Name,
+		typeName:    j.Type,
+		tag:         j.Tag,
+		tags:        parseTag(j.Tag),
+		comments:    j.Comments,
+		lineComment: j.LineComment,
+		embedded:    j.Embedded,
+		position:    positionVal(j.Position),
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the type parameter as JSON.
+func (tp TypeParam) MarshalJSON() ([]byte, error) {
+	return marshal(jsonTypeParam{
+		Name:       tp.name,
+		Constraint: tp.constraint,
+		Terms:      tp.terms,
+	})
+}
+
+// UnmarshalJSON decodes a type parameter that was encoded with TypeParam's MarshalJSON.
+func (tp *TypeParam) UnmarshalJSON(data []byte) error {
+	var j jsonTypeParam
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*tp = TypeParam{
+		name:       j.Name,
+		constraint: j.Constraint,
+		terms:      j.Terms,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the type term as JSON.
+func (tt TypeTerm) MarshalJSON() ([]byte, error) {
+	return marshal(jsonTypeTerm{
+		Type:  tt.typeName,
+		Tilde: tt.tilde,
+	})
+}
+
+// UnmarshalJSON decodes a type term that was encoded with TypeTerm's MarshalJSON.
+func (tt *TypeTerm) UnmarshalJSON(data []byte) error {
+	var j jsonTypeTerm
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*tt = TypeTerm{
+		typeName: j.Type,
+		tilde:    j.Tilde,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the test function as JSON.
+func (tf TestFunction) MarshalJSON() ([]byte, error) {
+	return marshal(jsonTestFunction{
+		Name:     tf.name,
+		Comments: tf.comments,
+		Position: positionPtr(tf.position),
+	})
+}
+
+// UnmarshalJSON decodes a test function that was encoded with TestFunction's MarshalJSON.
+func (tf *TestFunction) UnmarshalJSON(data []byte) error {
+	var j jsonTestFunction
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*tf = TestFunction{
+		name:     j.Name,
+		comments: j.Comments,
+		position: positionVal(j.Position),
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the example as JSON.
+func (e Example) MarshalJSON() ([]byte, error) {
+	return marshal(jsonExample{
+		Name:      e.name,
+		Suffix:    e.suffix,
+		Symbol:    e.symbol,
+		Comments:  e.comments,
+		Body:      e.body,
+		Output:    e.output,
+		HasOutput: e.hasOutput,
+		Unordered: e.unordered,
+		Position:  positionPtr(e.position),
+	})
+}
+
+// UnmarshalJSON decodes an example that was encoded with Example's MarshalJSON.
+func (e *Example) UnmarshalJSON(data []byte) error {
+	var j jsonExample
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*e = Example{
+		name:      j.Name,
+		suffix:    j.Suffix,
+		symbol:    j.Symbol,
+		comments:  j.Comments,
+		body:      j.Body,
+		output:    j.Output,
+		hasOutput: j.HasOutput,
+		unordered: j.Unordered,
+		position:  positionVal(j.Position),
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the position as JSON.
+func (p Position) MarshalJSON() ([]byte, error) {
+	return marshal(jsonPosition{
+		File:      p.file,
+		Line:      p.line,
+		Column:    p.column,
+		EndLine:   p.endLine,
+		EndColumn: p.endColumn,
+	})
+}
+
+// UnmarshalJSON decodes a position that was encoded with Position's MarshalJSON.
+func (p *Position) UnmarshalJSON(data []byte) error {
+	var j jsonPosition
+	if err := unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*p = Position{
+		file:      j.File,
+		line:      j.Line,
+		column:    j.Column,
+		endLine:   j.EndLine,
+		endColumn: j.EndColumn,
+	}
+
+	return nil
+}
+
+// marshal encodes v as JSON.
+func marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+
+	return b, nil
+}
+
+// unmarshal decodes the JSON in data into v.
+func unmarshal(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("json error: %w", err)
+	}
+
+	return nil
+}
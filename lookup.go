@@ -0,0 +1,380 @@
+// This file contains the logic for looking up a package's declarations by name.
+package pkg
+
+import (
+	"strings"
+)
+
+// SymbolKind is the kind of declaration that a Symbol holds.
+type SymbolKind int
+
+const (
+	// KindConstant is a constant.
+	KindConstant SymbolKind = iota + 1
+
+	// KindVariable is a variable that is not an error.
+	KindVariable
+
+	// KindError is a variable whose type implements the error interface.
+	KindError
+
+	// KindFunction is a function, including a constructor grouped under a type.
+	KindFunction
+
+	// KindType is a type.
+	KindType
+
+	// KindMethod is a method, including a method of an interface.
+	KindMethod
+
+	// KindField is a field of a struct.
+	KindField
+)
+
+// String returns the name of the kind, like "constant" or "method".
+func (k SymbolKind) String() string {
+	switch k {
+	case KindConstant:
+		return "constant"
+	case KindVariable:
+		return "variable"
+	case KindError:
+		return "error"
+	case KindFunction:
+		return "function"
+	case KindType:
+		return "type"
+	case KindMethod:
+		return "method"
+	case KindField:
+		return "field"
+	default:
+		return "unknown"
+	}
+}
+
+// LookupOptions holds the options for matching names in Symbols.
+type LookupOptions struct {
+	// Whether or not to match names regardless of case, so that "client.call" matches "Client.Call".
+	IgnoreCase bool
+
+	// Whether or not to match names that begin with the query, so that "Client.C" matches
+	// "Client.Call" and "Client.Close". Only the last part of a qualified name is matched as a prefix.
+	Prefix bool
+}
+
+// Symbol holds one declaration in a package: a constant, variable, error, function, type, method, or
+// field. Kind reports which one it is, and only the accessor for that kind returns it.
+type Symbol struct {
+	// Kind of declaration.
+	kind SymbolKind
+
+	// Qualified name, like "Open" or "File.Close".
+	name string
+
+	// Name of the type that the declaration belongs to, for methods, fields, and constructors.
+	typeName string
+
+	// Source of the declaration. For constants and variables, this is their whole block.
+	source string
+
+	// Documentation for the declaration. For constants and variables, this is their block's.
+	comments string
+
+	// Location of the declaration.
+	position Position
+
+	// Declaration itself. Only the one for kind is set, except that errors also set variable.
+	constant Constant
+	variable Variable
+	err      Error
+	function Function
+	typ      Type
+	method   Method
+	field    Field
+}
+
+// Lookup finds the declaration with the given name in the package, the same way as "go doc pkg sym".
+// name is either the name of a constant, variable, function, or type (like "Dial"), or the name of a
+// type followed by one of its methods or fields (like "Client.Call"). If no top-level declaration has
+// the name, the methods and fields of every type are searched as well. Names must match exactly. If
+// more than one declaration matches (like a method with the same name on several types), this returns
+// the first one in the order that Symbols uses.
+func (p Package) Lookup(name string) (Symbol, bool) {
+	symbols := p.Symbols(name, LookupOptions{})
+	if len(symbols) == 0 {
+		return Symbol{}, false
+	}
+
+	return symbols[0], true
+}
+
+// Symbols returns every declaration in the package whose name matches query, using the same rules as
+// Lookup and the matching options in opts. The declarations are in the same order as go doc lists
+// them: constants, variables, and functions, and then each type followed by its constructors, fields,
+// and methods.
+func (p Package) Symbols(query string, opts LookupOptions) []Symbol {
+	typeQuery, memberQuery, qualified := strings.Cut(query, ".")
+
+	// Only the last part of the name is matched as a prefix.
+	match := func(name string, query string, last bool) bool {
+		if opts.IgnoreCase {
+			name, query = strings.ToLower(name), strings.ToLower(query)
+		}
+		if opts.Prefix && last {
+			return strings.HasPrefix(name, query)
+		}
+
+		return name == query
+	}
+
+	all := p.allSymbols()
+	var symbols []Symbol
+	if !qualified {
+		for _, s := range all {
+			if s.kind != KindMethod && s.kind != KindField && match(s.name, query, true) {
+				symbols = append(symbols, s)
+			}
+		}
+		if len(symbols) > 0 {
+			return symbols
+		}
+	}
+
+	// Look for methods and fields, either on the named type or, for an unqualified name, on any type.
+	for _, s := range all {
+		if s.kind != KindMethod && s.kind != KindField {
+			continue
+		}
+		member := strings.TrimPrefix(s.name, s.typeName+".")
+		if !qualified && match(member, query, true) {
+			symbols = append(symbols, s)
+		} else if qualified && match(s.typeName, typeQuery, false) && match(member, memberQuery, true) {
+			symbols = append(symbols, s)
+		}
+	}
+
+	return symbols
+}
+
+// allSymbols returns every declaration in the package, in the order that Symbols uses.
+func (p Package) allSymbols() []Symbol {
+	var symbols []Symbol
+	for _, cb := range p.constantBlocks {
+		for _, c := range cb.constants {
+			symbols = append(symbols, Symbol{
+				kind:     KindConstant,
+				name:     c.name,
+				source:   cb.source,
+				comments: cb.comments,
+				position: c.position,
+				constant: c,
+			})
+		}
+	}
+	for _, vb := range p.variableBlocks {
+		errs := make(map[string]Error, len(vb.errors))
+		for _, e := range vb.errors {
+			errs[e.name] = e
+		}
+		for _, v := range vb.variables {
+			s := Symbol{
+				kind:     KindVariable,
+				name:     v.name,
+				source:   vb.source,
+				comments: vb.comments,
+				position: v.position,
+				variable: v,
+			}
+			if e, ok := errs[v.name]; ok {
+				s.kind = KindError
+				s.err = e
+			}
+			symbols = append(symbols, s)
+		}
+	}
+	for _, f := range p.functions {
+		symbols = append(symbols, functionSymbol(f, ""))
+	}
+
+	for _, t := range p.types {
+		symbols = append(symbols, Symbol{
+			kind:     KindType,
+			name:     t.name,
+			source:   t.source,
+			comments: t.comments,
+			position: t.position,
+			typ:      t,
+		})
+		for _, f := range t.functions {
+			symbols = append(symbols, functionSymbol(f, t.name))
+		}
+		for _, f := range t.fields {
+			source := f.typeName
+			if !f.embedded {
+				source = f.name + " " + f.typeName
+			}
+			if f.tag != "" {
+				source += " `" + f.tag + "`"
+			}
+			symbols = append(symbols, Symbol{
+				kind:     KindField,
+				name:     t.name + "." + f.name,
+				typeName: t.name,
+				source:   source,
+				comments: f.comments,
+				position: f.position,
+				field:    f,
+			})
+		}
+		for _, ms := range [][]Method{t.interfaceMethods, t.methods} {
+			for _, m := range ms {
+				symbols = append(symbols, Symbol{
+					kind:     KindMethod,
+					name:     t.name + "." + m.name,
+					typeName: t.name,
+					source:   m.source,
+					comments: m.comments,
+					position: m.position,
+					method:   m,
+				})
+			}
+		}
+	}
+
+	return symbols
+}
+
+// functionSymbol creates a Symbol for a function, which is grouped under the type typeName if it is a
+// constructor.
+func functionSymbol(f Function, typeName string) Symbol {
+	return Symbol{
+		kind:     KindFunction,
+		name:     f.name,
+		typeName: typeName,
+		source:   f.source,
+		comments: f.comments,
+		position: f.position,
+		function: f,
+	}
+}
+
+// Kind returns the kind of declaration that the symbol holds.
+func (s Symbol) Kind() SymbolKind {
+	return s.kind
+}
+
+// Name returns the symbol's qualified name, like "Open" for a function or "File.Close" for a method.
+func (s Symbol) Name() string {
+	return s.name
+}
+
+// TypeName returns the name of the type that the symbol belongs to for methods, fields, and
+// constructors, or "" for everything else.
+func (s Symbol) TypeName() string {
+	return s.typeName
+}
+
+// Source returns the source of the symbol's declaration. For constants and variables, this is the
+// whole block that declares them, the same as go doc prints. For fields, this is the field's name,
+// type, and tag.
+func (s Symbol) Source() string {
+	return s.source
+}
+
+// Synopsis returns a one-line summary of the symbol's declaration, like "func Open(name string)
+// (*File, error)", "type File struct", or "var EOF error". Unlike Source, this has only the symbol
+// itself for constants and variables, not the rest of their block.
+func (s Symbol) Synopsis() string {
+	switch s.kind {
+	case KindConstant:
+		return declSynopsis("const", s.constant.name, s.constant.typeName)
+	case KindVariable:
+		return declSynopsis("var", s.variable.name, s.variable.typeName)
+	case KindError:
+		return declSynopsis("var", s.err.name, "error")
+	case KindType:
+		return declSynopsis("type", s.typ.name, s.typ.typeName)
+	default:
+		return oneLine(s.source)
+	}
+}
+
+// declSynopsis returns a one-line summary of a declaration, like "var EOF error" or "type File struct".
+func declSynopsis(keyword string, name string, typeName string) string {
+	if typeName == "" {
+		return keyword + " " + name
+	}
+
+	return keyword + " " + name + " " + typeName
+}
+
+// Comments returns the documentation for the symbol's declaration with pkg's formatting applied. For
+// constants and variables, this is the documentation for their block.
+func (s Symbol) Comments(width int) string {
+	return formatComments(s.comments, width)
+}
+
+// Position returns the location of the symbol's declaration.
+func (s Symbol) Position() Position {
+	return s.position
+}
+
+// Constant returns the constant that the symbol holds, if it is a KindConstant.
+func (s Symbol) Constant() (Constant, bool) {
+	return s.constant, s.kind == KindConstant
+}
+
+// Variable returns the variable that the symbol holds, if it is a KindVariable or a KindError.
+func (s Symbol) Variable() (Variable, bool) {
+	return s.variable, s.kind == KindVariable || s.kind == KindError
+}
+
+// Error returns the error that the symbol holds, if it is a KindError.
+func (s Symbol) Error() (Error, bool) {
+	return s.err, s.kind == KindError
+}
+
+// Function returns the function that the symbol holds, if it is a KindFunction.
+func (s Symbol) Function() (Function, bool) {
+	return s.function, s.kind == KindFunction
+}
+
+// Type returns the type that the symbol holds, if it is a KindType.
+func (s Symbol) Type() (Type, bool) {
+	return s.typ, s.kind == KindType
+}
+
+// Method returns the method that the symbol holds, if it is a KindMethod.
+func (s Symbol) Method() (Method, bool) {
+	return s.method, s.kind == KindMethod
+}
+
+// Field returns the field that the symbol holds, if it is a KindField.
+func (s Symbol) Field() (Field, bool) {
+	return s.field, s.kind == KindField
+}
+
+// Value returns the declaration that the symbol holds: a Constant, Variable, Error, Function, Type,
+// Method, or Field, depending on its kind. This is useful for encoding the declaration, like with
+// encoding/json.
+func (s Symbol) Value() any {
+	switch s.kind {
+	case KindConstant:
+		return s.constant
+	case KindVariable:
+		return s.variable
+	case KindError:
+		return s.err
+	case KindFunction:
+		return s.function
+	case KindType:
+		return s.typ
+	case KindMethod:
+		return s.method
+	case KindField:
+		return s.field
+	default:
+		return nil
+	}
+}
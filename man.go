@@ -0,0 +1,239 @@
+// This file contains the logic for rendering a package's documentation as a man page.
+package pkg
+
+import (
+	"fmt"
+	"go/doc"
+	"go/doc/comment"
+	"io"
+	"strings"
+)
+
+// ManOptions holds the options for rendering a package as a man page.
+type ManOptions struct {
+	// Section of the manual that the page belongs to, like "3" or "3go". If empty, the page is in
+	// section 3 (library calls).
+	Section string
+
+	// Date that the page was last changed, in any format. If empty, no date is shown.
+	Date string
+
+	// Source of the package, like the name and version of the module that provides it. If empty, the
+	// package's module path and version are used.
+	Source string
+
+	// Title of the manual that the page belongs to. If empty, the title is "Go Packages".
+	Manual string
+}
+
+// manRenderer holds the state for rendering one package as a man page.
+type manRenderer struct {
+	// Output being built.
+	sb strings.Builder
+}
+
+// RenderMan writes the documentation for p to w as a man page in roff format, ready to be installed
+// and read with man. The page has a NAME section with the package's synopsis, a SYNOPSIS section with
+// the package's import path and the signatures of its exported declarations, a DESCRIPTION section
+// with the package's overview, and then CONSTANTS, VARIABLES, FUNCTIONS, and TYPES sections with a
+// subsection for each function and type.
+func RenderMan(w io.Writer, p Package, opts ManOptions) error {
+	r := manRenderer{}
+
+	title := p.importPath
+	if title == "" {
+		title = p.name
+	}
+	if opts.Section == "" {
+		opts.Section = "3"
+	}
+	if opts.Source == "" {
+		opts.Source = strings.TrimSpace(p.modulePath + " " + p.moduleVersion)
+	}
+	if opts.Manual == "" {
+		opts.Manual = "Go Packages"
+	}
+	fmt.Fprintf(&r.sb, ".TH %s %s %s %s %s\n", manQuote(strings.ToUpper(title)), manQuote(opts.Section),
+		manQuote(opts.Date), manQuote(opts.Source), manQuote(opts.Manual))
+
+	// The synopsis usually begins with "Package name", which would repeat the page's name.
+	synopsis := new(doc.Package).Synopsis(p.comments)
+	synopsis = strings.TrimPrefix(synopsis, "Package "+p.name+" ")
+	r.sb.WriteString(".SH NAME\n")
+	if synopsis != "" {
+		fmt.Fprintf(&r.sb, "%s \\- %s\n", manEscape(title), manEscape(synopsis))
+	} else {
+		r.sb.WriteString(manEscape(title) + "\n")
+	}
+
+	r.sb.WriteString(".SH SYNOPSIS\n.nf\n")
+	if p.importPath != "" {
+		r.line(fmt.Sprintf("import %q", p.importPath))
+		r.sb.WriteString("\n")
+	}
+	for _, cb := range p.constantBlocks {
+		r.line(blockSummary(cb.source))
+	}
+	for _, vb := range p.variableBlocks {
+		r.line(blockSummary(vb.source))
+	}
+	for _, f := range p.functions {
+		r.line(oneLine(f.source))
+	}
+	for _, t := range p.types {
+		r.line(typeSummary(t))
+		for _, f := range t.functions {
+			r.line(textIndent + oneLine(f.source))
+		}
+		for _, m := range t.methods {
+			r.line(textIndent + oneLine(m.source))
+		}
+	}
+	r.sb.WriteString(".fi\n")
+
+	if p.comments != "" {
+		r.sb.WriteString(".SH DESCRIPTION\n")
+		r.comments(p.comments)
+	}
+
+	if len(p.constantBlocks) > 0 {
+		r.sb.WriteString(".SH CONSTANTS\n")
+		for _, cb := range p.constantBlocks {
+			r.declaration(cb.source, cb.comments)
+		}
+	}
+	if len(p.variableBlocks) > 0 {
+		r.sb.WriteString(".SH VARIABLES\n")
+		for _, vb := range p.variableBlocks {
+			r.declaration(vb.source, vb.comments)
+		}
+	}
+	if len(p.functions) > 0 {
+		r.sb.WriteString(".SH FUNCTIONS\n")
+		for _, f := range p.functions {
+			r.subsection("func " + f.name)
+			r.declaration(f.source, f.comments)
+		}
+	}
+	if len(p.types) > 0 {
+		r.sb.WriteString(".SH TYPES\n")
+		for _, t := range p.types {
+			r.subsection("type " + t.name)
+			r.declaration(t.source, t.comments)
+			for _, f := range t.functions {
+				r.declaration(f.source, f.comments)
+			}
+			for _, m := range t.methods {
+				r.declaration(m.source, m.comments)
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, r.sb.String()); err != nil {
+		return fmt.Errorf("error writing man page: %w", err)
+	}
+
+	return nil
+}
+
+// subsection renders the heading of a subsection.
+func (r *manRenderer) subsection(heading string) {
+	fmt.Fprintf(&r.sb, ".SS %s\n", manQuote(heading))
+}
+
+// declaration renders the source for a declaration in bold, followed by its indented documentation.
+func (r *manRenderer) declaration(source string, comments string) {
+	r.sb.WriteString(".PP\n.nf\n")
+	for _, line := range strings.Split(strings.TrimRight(source, "\n"), "\n") {
+		r.sb.WriteString("\\fB" + strings.ReplaceAll(line, "\\", "\\e") + "\\fR\n")
+	}
+	r.sb.WriteString(".fi\n")
+
+	if strings.TrimSpace(comments) != "" {
+		r.sb.WriteString(".RS\n")
+		r.comments(comments)
+		r.sb.WriteString(".RE\n")
+	}
+}
+
+// line renders a single line of text exactly as it is.
+func (r *manRenderer) line(s string) {
+	r.sb.WriteString(manEscape(s) + "\n")
+}
+
+// comments renders documentation as paragraphs, headings, lists, and blocks of code.
+func (r *manRenderer) comments(text string) {
+	d := new(comment.Parser).Parse(text)
+	for _, block := range d.Content {
+		switch b := block.(type) {
+		case *comment.Paragraph:
+			r.sb.WriteString(".PP\n")
+			r.line(manText(b.Text))
+		case *comment.Heading:
+			r.sb.WriteString(".PP\n.B\n")
+			r.line(manText(b.Text))
+		case *comment.Code:
+			r.sb.WriteString(".PP\n.RS\n.nf\n")
+			for _, line := range strings.Split(strings.TrimRight(b.Text, "\n"), "\n") {
+				r.line(line)
+			}
+			r.sb.WriteString(".fi\n.RE\n")
+		case *comment.List:
+			for i, item := range b.Items {
+				bullet := "\\(bu"
+				if item.Number != "" {
+					bullet = item.Number + "."
+				}
+				for j, content := range item.Content {
+					if p, ok := content.(*comment.Paragraph); ok {
+						if j == 0 {
+							fmt.Fprintf(&r.sb, ".IP %s 4\n", bullet)
+						} else {
+							r.sb.WriteString(".IP \"\" 4\n")
+						}
+						r.line(manText(p.Text))
+					}
+				}
+				if i == len(b.Items)-1 {
+					r.sb.WriteString(".PP\n")
+				}
+			}
+		}
+	}
+}
+
+// manText returns the plain text of a span of documentation, joined into a single line.
+func manText(text []comment.Text) string {
+	sb := new(strings.Builder)
+	for _, t := range text {
+		switch t := t.(type) {
+		case comment.Plain:
+			sb.WriteString(string(t))
+		case comment.Italic:
+			sb.WriteString(string(t))
+		case *comment.Link:
+			sb.WriteString(manText(t.Text))
+		case *comment.DocLink:
+			sb.WriteString(manText(t.Text))
+		}
+	}
+
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// manEscape escapes a line of text so that roff prints it as it is.
+func manEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\e")
+
+	// Lines that begin with a period or apostrophe would be read as requests.
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = "\\&" + s
+	}
+
+	return s
+}
+
+// manQuote returns s as a quoted argument to a roff request.
+func manQuote(s string) string {
+	return "\"" + strings.ReplaceAll(manEscape(s), "\"", "\\(dq") + "\""
+}
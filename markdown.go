@@ -0,0 +1,263 @@
+// This file contains the logic for rendering a package's documentation as Markdown.
+package pkg
+
+import (
+	"fmt"
+	"go/doc/comment"
+	"io"
+	"strings"
+)
+
+// MarkdownOptions holds the options for rendering a package as Markdown.
+type MarkdownOptions struct {
+	// Level of the package's heading, from 1 to 6. Each section is one level below the package's
+	// heading, and each item in a section is one level below that. If 0, the package's heading is a
+	// level 1 heading.
+	HeadingLevel int
+
+	// Sections to render. If 0, every section is rendered.
+	Sections Section
+}
+
+// markdownRenderer holds the state for rendering one package as Markdown.
+type markdownRenderer struct {
+	// Package being rendered.
+	p Package
+
+	// Output being built.
+	sb strings.Builder
+
+	// Level of the package's heading.
+	level int
+
+	// Sections to render.
+	sections Section
+}
+
+// RenderMarkdown writes the documentation for p to w as a Markdown API reference. The reference can
+// include the package's overview, an index, and the package's constants, variables, functions, and
+// types (with their constructors and methods), depending on which sections are selected in opts. Every
+// function, type, and method has an anchor with the same name that pkg.go.dev uses, like "NewReader"
+// or "Reader.Read", and all source is written in fenced Go code blocks.
+func RenderMarkdown(w io.Writer, p Package, opts MarkdownOptions) error {
+	r := markdownRenderer{
+		p:        p,
+		level:    opts.HeadingLevel,
+		sections: opts.Sections,
+	}
+	if r.level <= 0 {
+		r.level = 1
+	}
+	if r.sections == 0 {
+		r.sections = SectionAll
+	}
+
+	if r.sections.Has(SectionOverview) {
+		r.renderOverview()
+	}
+	if r.sections.Has(SectionIndex) {
+		r.renderIndex()
+	}
+	if r.sections.Has(SectionConstants) {
+		r.renderConstants()
+	}
+	if r.sections.Has(SectionVariables) {
+		r.renderVariables()
+	}
+	if r.sections.Has(SectionFunctions) {
+		r.renderFunctions()
+	}
+	if r.sections.Has(SectionTypes) {
+		r.renderTypes()
+	}
+
+	if _, err := io.WriteString(w, r.sb.String()); err != nil {
+		return fmt.Errorf("error writing markdown: %w", err)
+	}
+
+	return nil
+}
+
+// renderOverview renders the package's heading, import path, and overview comments.
+func (r *markdownRenderer) renderOverview() {
+	r.heading(r.level, "pkg-overview", "Package "+r.p.name)
+	if r.p.importPath != "" {
+		fmt.Fprintf(&r.sb, "```go\nimport %q\n```\n\n", r.p.importPath)
+	}
+	r.comments(r.p.comments, r.level+1)
+}
+
+// renderIndex renders the list of links to everything in the package.
+func (r *markdownRenderer) renderIndex() {
+	r.heading(r.level+1, "pkg-index", "Index")
+	if len(r.p.constantBlocks) > 0 {
+		r.sb.WriteString("- [Constants](#pkg-constants)\n")
+	}
+	if len(r.p.variableBlocks) > 0 {
+		r.sb.WriteString("- [Variables](#pkg-variables)\n")
+	}
+	for _, f := range r.p.functions {
+		r.indexEntry("", f.name, f.source)
+	}
+	for _, t := range r.p.types {
+		r.indexEntry("", t.name, "type "+t.name)
+		for _, f := range t.functions {
+			r.indexEntry("  ", f.name, f.source)
+		}
+		for _, m := range t.methods {
+			r.indexEntry("  ", t.name+"."+m.name, m.source)
+		}
+	}
+	r.sb.WriteString("\n")
+}
+
+// indexEntry renders a single link in the index.
+func (r *markdownRenderer) indexEntry(indent string, anchor string, source string) {
+	fmt.Fprintf(&r.sb, "%s- [%s](#%s)\n", indent, markdownEscape(oneLine(source)), anchor)
+}
+
+// renderConstants renders every block of constants.
+func (r *markdownRenderer) renderConstants() {
+	if len(r.p.constantBlocks) == 0 {
+		return
+	}
+
+	r.heading(r.level+1, "pkg-constants", "Constants")
+	for _, cb := range r.p.constantBlocks {
+		names := make([]string, len(cb.constants))
+		for i, c := range cb.constants {
+			names[i] = c.name
+		}
+		r.anchors(names)
+		r.code(cb.source)
+		r.comments(cb.comments, r.level+2)
+	}
+}
+
+// renderVariables renders every block of variables.
+func (r *markdownRenderer) renderVariables() {
+	if len(r.p.variableBlocks) == 0 {
+		return
+	}
+
+	r.heading(r.level+1, "pkg-variables", "Variables")
+	for _, vb := range r.p.variableBlocks {
+		names := make([]string, len(vb.variables))
+		for i, v := range vb.variables {
+			names[i] = v.name
+		}
+		r.anchors(names)
+		r.code(vb.source)
+		r.comments(vb.comments, r.level+2)
+	}
+}
+
+// renderFunctions renders every function that is not grouped under a type.
+func (r *markdownRenderer) renderFunctions() {
+	if len(r.p.functions) == 0 {
+		return
+	}
+
+	r.heading(r.level+1, "pkg-functions", "Functions")
+	for _, f := range r.p.functions {
+		r.function(f, r.level+2)
+	}
+}
+
+// renderTypes renders every type with its constructors and methods.
+func (r *markdownRenderer) renderTypes() {
+	if len(r.p.types) == 0 {
+		return
+	}
+
+	r.heading(r.level+1, "pkg-types", "Types")
+	for _, t := range r.p.types {
+		r.heading(r.level+2, t.name, "type "+t.name)
+		r.code(t.source)
+		r.comments(t.comments, r.level+3)
+
+		for _, f := range t.functions {
+			r.function(f, r.level+3)
+		}
+		for _, m := range t.methods {
+			r.heading(r.level+3, t.name+"."+m.name, "func ("+m.receiver.String()+") "+m.name)
+			r.code(m.source)
+			r.comments(m.comments, r.level+4)
+		}
+	}
+}
+
+// function renders a single function at the given heading level.
+func (r *markdownRenderer) function(f Function, level int) {
+	r.heading(level, f.name, "func "+f.name)
+	r.code(f.source)
+	r.comments(f.comments, level+1)
+}
+
+// heading renders a heading with an anchor. Markdown only has six levels of headings, so deeper
+// headings are rendered as level 6 headings.
+func (r *markdownRenderer) heading(level int, anchor string, text string) {
+	if level > 6 {
+		level = 6
+	}
+
+	fmt.Fprintf(&r.sb, "%s <a id=\"%s\"></a>%s\n\n", strings.Repeat("#", level), anchor, markdownEscape(text))
+}
+
+// anchors renders an anchor for each name, so that the items in a block of constants or variables can
+// be linked to.
+func (r *markdownRenderer) anchors(names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	for _, name := range names {
+		fmt.Fprintf(&r.sb, "<a id=\"%s\"></a>", name)
+	}
+	r.sb.WriteString("\n\n")
+}
+
+// code renders source in a fenced Go code block.
+func (r *markdownRenderer) code(source string) {
+	source = strings.TrimRight(source, "\n")
+	if source == "" {
+		return
+	}
+
+	fmt.Fprintf(&r.sb, "```go\n%s\n```\n\n", source)
+}
+
+// comments renders documentation as Markdown. Headings within the documentation are rendered at the
+// given level, and links to other items in this package (like "[Reader]") point to their anchors.
+func (r *markdownRenderer) comments(text string, level int) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	if level > 6 {
+		level = 6
+	}
+
+	printer := comment.Printer{
+		HeadingLevel: level,
+		DocLinkURL: func(link *comment.DocLink) string {
+			return docLinkURL(link, defaultPackageURL)
+		},
+	}
+
+	r.sb.Write(printer.Markdown(newCommentParser(r.p).Parse(text)))
+	r.sb.WriteString("\n")
+}
+
+// markdownEscape escapes the characters in s that have a special meaning in Markdown.
+func markdownEscape(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune("\\`*_[]<>#|", c) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(c)
+	}
+
+	return sb.String()
+}
@@ -2,8 +2,12 @@
 package pkg
 
 import (
+	"bytes"
+	"go/ast"
 	"go/doc"
 	"go/token"
+	"go/types"
+	"strings"
 )
 
 // Method holds information about a type's method.
@@ -17,24 +21,43 @@ type Method struct {
 	// Receiver of this method.
 	receiver Parameter
 
+	// Type parameters of the receiver, if the method's type is generic.
+	typeParams []TypeParam
+
+	// Source of this method's signature, without the body.
+	source string
+
 	// Input parameters.
 	inputs []Parameter
 
 	// Output parameters.
 	outputs []Parameter
+
+	// Location of this method's declaration.
+	position Position
+
+	// Build constraint that this method is declared under, or "" if it is built everywhere.
+	constraint string
+
+	// Examples of this method from the package's test files.
+	examples []Example
 }
 
-// newMethod builds a new Method object based on go/doc's Func.
-func newMethod(m *doc.Func, fset *token.FileSet) Method {
+// newMethod builds a new Method object based on go/doc's Func. typeParams is the list of type
+// parameters declared by the method's type, which provides the constraints for the receiver's type
+// parameters.
+func newMethod(m *doc.Func, fset *token.FileSet, typeParams []TypeParam) Method {
 	if m == nil {
 		return Method{}
 	}
 
 	// Extract the receiver.
 	var receiver Parameter
+	var receiverTypeParams []TypeParam
 	receivers := newParameters(m.Decl.Recv, fset)
 	if len(receivers) > 0 {
 		receiver = receivers[0]
+		receiverTypeParams = extractReceiverTypeParams(m.Decl.Recv.List[0].Type, typeParams)
 	}
 
 	// Extract the parameters.
@@ -42,11 +65,101 @@ func newMethod(m *doc.Func, fset *token.FileSet) Method {
 	out := newParameters(m.Decl.Type.Results, fset)
 
 	return Method{
-		name:     m.Name,
-		comments: m.Doc,
+		name:       m.Name,
+		comments:   m.Doc,
+		receiver:   receiver,
+		typeParams: receiverTypeParams,
+		source:     extractFuncSource(m.Decl, fset),
+		inputs:     in,
+		outputs:    out,
+		position:   newPosition(m.Decl.Pos(), m.Decl.End(), fset),
+	}
+}
+
+// extractReceiverTypeParams extracts the type parameters from a receiver like "l *List[T]". The
+// receiver only names the type parameters, so the constraints are taken from the matching type
+// parameters in the type's declaration.
+func extractReceiverTypeParams(recv ast.Expr, typeParams []TypeParam) []TypeParam {
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+
+	var indices []ast.Expr
+	switch r := recv.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{r.Index}
+	case *ast.IndexListExpr:
+		indices = r.Indices
+	default:
+		return nil
+	}
+
+	params := make([]TypeParam, 0, len(indices))
+	for i, index := range indices {
+		ident, ok := index.(*ast.Ident)
+		if !ok {
+			continue
+		}
+
+		param := TypeParam{name: ident.Name}
+		if i < len(typeParams) {
+			param.constraint = typeParams[i].constraint
+			param.terms = typeParams[i].terms
+		}
+		params = append(params, param)
+	}
+
+	return params
+}
+
+// newInterfaceMethod builds a new Method object for a method declared in an interface type. The
+// method's receiver is the interface type itself.
+func newInterfaceMethod(f *ast.Field, name string, typeName string, fset *token.FileSet) Method {
+	ft, ok := f.Type.(*ast.FuncType)
+	if !ok {
+		return Method{}
+	}
+
+	return Method{
+		name:     name,
+		comments: f.Doc.Text(),
+		receiver: Parameter{typeName: typeName},
+		source:   name + strings.TrimPrefix(extractSource(ft, fset), "func"),
+		inputs:   newParameters(ft.Params, fset),
+		outputs:  newParameters(ft.Results, fset),
+		position: newPosition(f.Pos(), f.End(), fset),
+	}
+}
+
+// newMethodFromFunc builds a new Method object based on go/types' Func. This is used for methods that
+// are only known through type-checking, like methods of interfaces from other packages. These methods
+// do not have any comments or positions.
+func newMethodFromFunc(f *types.Func, ti *typeInfo) Method {
+	sig, ok := f.Type().(*types.Signature)
+	if !ok {
+		return Method{}
+	}
+
+	// For interface methods, the receiver is the interface that declared the method.
+	var receiver Parameter
+	if recv := sig.Recv(); recv != nil {
+		receiver = Parameter{
+			name:     recv.Name(),
+			typeName: ti.typeString(recv.Type()),
+		}
+	}
+
+	// Like methods declared in an interface, the source is the method's name and signature.
+	source := new(bytes.Buffer)
+	source.WriteString(f.Name())
+	types.WriteSignature(source, sig, ti.qualifier)
+
+	return Method{
+		name:     f.Name(),
 		receiver: receiver,
-		inputs:   in,
-		outputs:  out,
+		source:   source.String(),
+		inputs:   ti.newTupleParameters(sig.Params(), sig.Variadic()),
+		outputs:  ti.newTupleParameters(sig.Results(), false),
 	}
 }
 
@@ -55,6 +168,11 @@ func (m Method) Name() string {
 	return m.name
 }
 
+// Exported reports whether or not this method is exported.
+func (m Method) Exported() bool {
+	return ast.IsExported(m.name)
+}
+
 // Comments returns the documentation for this method with pkg's formatting applied.
 func (m Method) Comments(width int) string {
 	return formatComments(m.comments, width)
@@ -70,6 +188,35 @@ func (m Method) PointerReceiver() bool {
 	return m.receiver.Pointer()
 }
 
+// Position returns the location of the method's declaration, including its body. Methods that are
+// only known through type checking, like those of embedded interfaces from other packages, have no
+// position.
+func (m Method) Position() Position {
+	return m.position
+}
+
+// Constraint returns the build constraint that the method is declared under, like "linux && amd64",
+// or "" if it is built everywhere. If the method is declared in more than one file (like once for
+// each operating system), the constraint is satisfied when any of those files is built. Methods
+// declared in an interface have the same constraint as the interface.
+func (m Method) Constraint() string {
+	return m.constraint
+}
+
+// Source returns the source of the method's signature without its body, like
+// "func (f *File) Close() error". For methods declared in an interface, this is the method's name and
+// signature as written in the interface, like "Close() error".
+func (m Method) Source() string {
+	return m.source
+}
+
+// TypeParams returns a list of the type parameters named by the method's receiver if the method's
+// type is generic, like T for the receiver "l *List[T]", or an empty list otherwise. The constraints
+// come from the type's declaration.
+func (m Method) TypeParams() []TypeParam {
+	return append([]TypeParam{}, m.typeParams...)
+}
+
 // Inputs returns a list of input parameters sent to this method, or nil on invalid object. If
 // there are no input parameters, this returns a slice of size 0.. The list does not include the
 // method's receiver.
@@ -82,3 +229,8 @@ func (m Method) Inputs() []Parameter {
 func (m Method) Outputs() []Parameter {
 	return append([]Parameter{}, m.outputs...)
 }
+
+// Examples returns a list of examples of this method from the package's test files.
+func (m Method) Examples() []Example {
+	return append([]Example{}, m.examples...)
+}
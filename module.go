@@ -0,0 +1,299 @@
+// This file contains the logic for loading packages the way the go command does in module mode.
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"go/doc"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Config holds the options for loading packages. Load resolves packages in module mode with the go
+// command and is the only method that uses every field. Config also has the other loaders of this
+// package as methods, like NewFromDir and Walk, which find packages the same way as the functions of
+// the same names. Those use only AllDecls and ignore Dir, BuildFlags, and Env. The zero value is
+// ready to use and resolves import paths relative to the current working directory with the current
+// environment.
+type Config struct {
+	// Working directory in which the go command is run by Load. Import paths are resolved using the
+	// module (or workspace) that contains this directory, including its replace directives and vendor
+	// directory. If empty, the current working directory is used.
+	Dir string
+
+	// Command-line flags passed through to the go command by Load, like "-tags=purego" or
+	// "-mod=vendor".
+	BuildFlags []string
+
+	// Environment for the go command that Load runs, in the form "key=value". If nil, the current
+	// process's environment is used. GO111MODULE=on is added unless the environment already sets
+	// GO111MODULE.
+	Env []string
+
+	// Whether or not to include unexported constants, variables, functions, types, fields, and methods
+	// along with the exported ones, like "go doc -u". Each of these has an Exported method to tell
+	// them apart. This applies to every method of Config that loads packages.
+	AllDecls bool
+}
+
+// listPackage holds the parts of the go command's "go list -json" output that we need to build a
+// Package object.
+type listPackage struct {
+	Dir            string
+	ImportPath     string
+	Name           string
+	GoFiles        []string
+	CgoFiles       []string
+	IgnoredGoFiles []string
+	TestGoFiles    []string
+	XTestGoFiles   []string
+	Imports        []string
+	TestImports    []string
+	XTestImports   []string
+	Module         *listModule
+	Error          *listError
+}
+
+// listModule holds the module information from the go command's "go list -json" output.
+type listModule struct {
+	Path    string
+	Version string
+	Dir     string
+	Replace *listModule
+}
+
+// listError holds an error from the go command's "go list -json" output.
+type listError struct {
+	Err string
+}
+
+// Load parses the package matched by pattern and creates a new Package object with its information.
+// Unlike New, which uses the legacy go/build resolver, Load asks the go command to resolve pattern
+// in module mode, so go.mod replace directives, vendor directories, and go.work workspaces are all
+// honored. pattern may be an import path or a directory relative to c's Dir (like "./internal/foo"),
+// but it must match exactly one package. The package's dependencies are resolved the same way for type
+// checking, so the types of declarations that come from other modules are known too.
+func (c Config) Load(pattern string) (Package, error) {
+	listPkgs, err := c.list(pattern)
+	if err != nil {
+		return Package{}, err
+	}
+
+	if len(listPkgs) == 0 {
+		return Package{}, fmt.Errorf("%w: %s", ErrNotFound, pattern)
+	} else if len(listPkgs) > 1 {
+		return Package{}, fmt.Errorf("pattern %s matches %v packages", pattern, len(listPkgs))
+	}
+
+	return loadListPackage(listPkgs[0], c.docMode(), c.importer(pattern))
+}
+
+// New is like the package-level New, but includes unexported declarations if c's AllDecls is set. The
+// package is found with go/build the same way as by New, and c's Dir, BuildFlags, and Env are not
+// used. To use those, see Config's Load.
+func (c Config) New(importPath string) (Package, error) {
+	return newFromImportPath(importPath, c.docMode())
+}
+
+// NewFromDir is like the package-level NewFromDir, but includes unexported declarations if c's
+// AllDecls is set. c's Dir, BuildFlags, and Env are not used.
+func (c Config) NewFromDir(dir string) (Package, error) {
+	return newFromDir(dir, c.docMode())
+}
+
+// NewFromSources is like the package-level NewFromSources, but includes unexported declarations if c's
+// AllDecls is set. c's Dir, BuildFlags, and Env are not used.
+func (c Config) NewFromSources(sources map[string][]byte) (Package, error) {
+	return newFromSources(sources, c.docMode())
+}
+
+// NewTree is like the package-level NewTree, but includes unexported declarations if c's AllDecls is
+// set. c's Dir, BuildFlags, and Env are not used.
+func (c Config) NewTree(root string) (Tree, error) {
+	return newTree(root, c.docMode())
+}
+
+// Walk is like the package-level Walk, but includes unexported declarations if c's AllDecls is set.
+// c's Dir, BuildFlags, and Env are not used.
+func (c Config) Walk(pattern string, fn func(Package) error) error {
+	return walk(pattern, c.docMode(), func(_ string, p Package) error {
+		return fn(p)
+	})
+}
+
+// docMode returns the go/doc mode for the config's options.
+func (c Config) docMode() doc.Mode {
+	if c.AllDecls {
+		return doc.AllDecls | doc.AllMethods
+	}
+
+	return 0
+}
+
+// importer returns an importer for the dependencies of the packages matched by pattern. It finds the
+// export data of every dependency with one run of "go list -export", so dependencies are resolved from
+// c's module the same way as the packages themselves and are built with c's build flags.
+func (c Config) importer(pattern string) types.Importer {
+	var exports map[string]string
+	lookup := func(importPath string) (io.ReadCloser, error) {
+		if exports == nil {
+			exports = c.exports(pattern)
+		}
+
+		// Packages vendored into the standard library are listed under their vendor directory.
+		file, ok := exports[importPath]
+		if !ok {
+			file, ok = exports["vendor/"+importPath]
+		}
+		if !ok || file == "" {
+			return nil, fmt.Errorf("no export data for %s", importPath)
+		}
+
+		return os.Open(file)
+	}
+
+	return importer.ForCompiler(token.NewFileSet(), "gc", lookup)
+}
+
+// exports returns the paths to the export data of the packages matched by pattern and all of their
+// dependencies, building them first if needed, keyed by import path. Packages that cannot be built
+// have no export data.
+func (c Config) exports(pattern string) map[string]string {
+	args := append([]string{"list", "-e", "-deps", "-export", "-f", "{{.ImportPath}}\t{{.Export}}"}, c.BuildFlags...)
+	args = append(args, "--", pattern)
+
+	exports := make(map[string]string)
+	stdout, err := c.run(args...)
+	if err != nil {
+		return exports
+	}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if importPath, file, ok := strings.Cut(line, "\t"); ok {
+			exports[importPath] = file
+		}
+	}
+
+	return exports
+}
+
+// run runs the go command with args in c's directory and environment and returns what it wrote to
+// stdout. If the command fails, the returned error includes what it wrote to stderr.
+func (c Config) run(args ...string) (*bytes.Buffer, error) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = c.Dir
+	cmd.Env = os.Environ()
+	if c.Env != nil {
+		// Copy the caller's environment so that adding to it below doesn't change their slice.
+		cmd.Env = append([]string(nil), c.Env...)
+	}
+	if !hasEnv(cmd.Env, "GO111MODULE") {
+		cmd.Env = append(cmd.Env, "GO111MODULE=on")
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return stdout, nil
+}
+
+// hasEnv reports whether or not env, in the form "key=value", sets the variable key.
+func hasEnv(env []string, key string) bool {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// list runs "go list" on pattern and decodes the information for every matched package.
+func (c Config) list(pattern string) ([]listPackage, error) {
+	args := append([]string{"list", "-e", "-json"}, c.BuildFlags...)
+	args = append(args, "--", pattern)
+
+	stdout, err := c.run(args...)
+	if err != nil {
+		return nil, fmt.Errorf("go list error in %s: %w", pattern, err)
+	}
+
+	// The go command writes one JSON object per package, one after the other.
+	var listPkgs []listPackage
+	dec := json.NewDecoder(stdout)
+	for {
+		var listPkg listPackage
+		if err := dec.Decode(&listPkg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid go list output for %s: %w", pattern, err)
+		}
+		listPkgs = append(listPkgs, listPkg)
+	}
+
+	return listPkgs, nil
+}
+
+// loadListPackage parses the package described by the go command's output and creates a new Package
+// object with its information. mode is passed to go/doc and controls which declarations are included,
+// and imp imports the package's dependencies for type checking.
+func loadListPackage(listPkg listPackage, mode doc.Mode, imp types.Importer) (Package, error) {
+	// The go command only reports a directory for packages that it could find.
+	if listPkg.Error != nil && listPkg.Dir == "" {
+		return Package{}, fmt.Errorf("%w: %s: %s", ErrNotFound, listPkg.ImportPath, listPkg.Error.Err)
+	} else if listPkg.Error != nil {
+		return Package{}, fmt.Errorf("build error: invalid package in %s: %s", listPkg.ImportPath, listPkg.Error.Err)
+	}
+
+	// Convert the go command's output into the go/build Package that the rest of the pipeline uses.
+	buildPkg := &build.Package{
+		Dir:            listPkg.Dir,
+		ImportPath:     listPkg.ImportPath,
+		Name:           listPkg.Name,
+		GoFiles:        listPkg.GoFiles,
+		CgoFiles:       listPkg.CgoFiles,
+		IgnoredGoFiles: listPkg.IgnoredGoFiles,
+		TestGoFiles:    listPkg.TestGoFiles,
+		XTestGoFiles:   listPkg.XTestGoFiles,
+		Imports:        listPkg.Imports,
+		TestImports:    listPkg.TestImports,
+		XTestImports:   listPkg.XTestImports,
+	}
+
+	fset := token.NewFileSet()
+	astPkgs, err := parser.ParseDir(fset, buildPkg.Dir, nil, parser.ParseComments)
+	if err != nil {
+		return Package{}, fmt.Errorf("invalid package in %s: %w", buildPkg.ImportPath, err)
+	}
+
+	pkg, err := parsePackage(buildPkg, astPkgs, fset, buildPkg.ImportPath, mode, imp)
+	if err != nil {
+		return Package{}, err
+	}
+
+	// Packages in the standard library do not belong to a module.
+	if m := listPkg.Module; m != nil {
+		pkg.modulePath = m.Path
+		pkg.moduleVersion = m.Version
+		pkg.moduleRoot = m.Dir
+		if m.Replace != nil && pkg.moduleRoot == "" {
+			pkg.moduleRoot = m.Replace.Dir
+		}
+	}
+
+	return pkg, nil
+}
@@ -3,6 +3,7 @@ package pkg
 
 import (
 	"go/ast"
+	"go/parser"
 	"go/token"
 	"strings"
 )
@@ -14,6 +15,9 @@ type Parameter struct {
 
 	// Name of the parameter's type.
 	typeName string
+
+	// Location of the parameter's declaration.
+	position Position
 }
 
 // newParameters extracts all parameters for the given list of fields.
@@ -33,6 +37,7 @@ func newParameters(list *ast.FieldList, fset *token.FileSet) []Parameter {
 			params = append(params, Parameter{
 				name:     "",
 				typeName: typeName,
+				position: newPosition(p.Pos(), p.End(), fset),
 			})
 		} else {
 			// Get the names of all grouped parameters of this type and add the members to the list.
@@ -40,6 +45,7 @@ func newParameters(list *ast.FieldList, fset *token.FileSet) []Parameter {
 				params = append(params, Parameter{
 					name:     strings.TrimSpace(name.Name),
 					typeName: typeName,
+					position: newPosition(name.Pos(), p.End(), fset),
 				})
 			}
 		}
@@ -58,6 +64,13 @@ func (p Parameter) Type() string {
 	return p.typeName
 }
 
+// Position returns the location of the parameter's declaration, from the parameter's name (or type,
+// for unnamed parameters) to the end of its type. Parameters that are only known through type
+// checking, like those of methods from embedded interfaces in other packages, have no position.
+func (p Parameter) Position() Position {
+	return p.position
+}
+
 // Pointer reports whether or not this parameter is a pointer. If the parameter is a slice of
 // pointers, this returns false.
 func (p Parameter) Pointer() bool {
@@ -67,6 +80,36 @@ func (p Parameter) Pointer() bool {
 	return strings.HasPrefix(s, "*")
 }
 
+// TypeArgs returns a list of the type arguments in the parameter's type, like ["K", "V"] for the type
+// "*Map[K, V]", or an empty list if the type is not an instantiated generic type.
+func (p Parameter) TypeArgs() []string {
+	// Remove the ... prefix for variadic parameters.
+	s := strings.TrimPrefix(p.typeName, "...")
+
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return []string{}
+	}
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+
+	var indices []ast.Expr
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		indices = e.Indices
+	}
+
+	args := make([]string, len(indices))
+	for i, index := range indices {
+		args[i] = s[index.Pos()-1 : index.End()-1]
+	}
+
+	return args
+}
+
 // String returns the string representation of this type.
 func (p Parameter) String() string {
 	s := p.name + " " + p.typeName
@@ -3,19 +3,27 @@
 package pkg
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/build"
 	"go/doc"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 )
 
 var ErrInvalidPkg = fmt.Errorf("invalid package")
 
+// ErrNotFound is returned when there is no package at the given import path or directory. Errors for
+// packages that exist but cannot be built or parsed do not wrap this.
+var ErrNotFound = fmt.Errorf("package not found")
+
 // Package is the main type for this package. It holds details about the package's structure.
 type Package struct {
 	// Name of this package.
@@ -27,10 +35,26 @@ type Package struct {
 	// General package overview comments/documentation.
 	comments string
 
+	// Path of the module that contains this package, if the package was loaded in module mode.
+	modulePath string
+
+	// Version of the module that contains this package. This is empty for the main module. For a
+	// module replaced by a local directory, this is the version that was replaced.
+	moduleVersion string
+
+	// Root directory of the module that contains this package.
+	moduleRoot string
+
+	// Directory that contains this package's source files.
+	dir string
+
 	// List of source files for this package. This includes both the source files for this system's
 	// build and those ignored for this system's build.
 	files []string
 
+	// Build constraints for the source files that have them, keyed by file name.
+	constraints map[string]string
+
 	// List of test files for this package. This includes both the test files within this package
 	// and the test files for any other external test package in this package's directory.
 	testFiles []string
@@ -45,27 +69,46 @@ type Package struct {
 	// files in the package and test files not in the package but in the package's directory.
 	testImports []string
 
-	// List of groups of one or more exported constants in this package.
+	// List of groups of one or more exported constants in this package (or all constants, if
+	// unexported declarations were requested).
 	constantBlocks []ConstantBlock
 
-	// List of groups of one or more exported variables in this package.
+	// List of groups of one or more exported variables in this package (or all variables, if
+	// unexported declarations were requested).
 	variableBlocks []VariableBlock
 
-	// List of exported functions for this package. This includes only exported functions from the
-	// source files, not from the test files.
+	// List of exported functions for this package (or all functions, if unexported declarations were
+	// requested). This includes only functions from the source files, not from the test files.
 	functions []Function
 
-	// List of exported types for this package. This includes only exported types from the source
-	// files, not from the test files.
+	// List of exported types for this package (or all types, if unexported declarations were
+	// requested). This includes only types from the source files, not from the test files.
 	types []Type
+
+	// List of tests, benchmarks, and fuzz targets in the test files for this package. This includes
+	// the test files for any other external test package in this package's directory.
+	tests       []TestFunction
+	benchmarks  []TestFunction
+	fuzzTargets []TestFunction
+
+	// List of examples in the test files for this package, sorted by name.
+	examples []Example
 }
 
 // New parses the package at importPath and creates a new Package object with its information.
 func New(importPath string) (Package, error) {
+	return newFromImportPath(importPath, 0)
+}
+
+// newFromImportPath does the work for New. mode is passed to go/doc and controls which declarations
+// are included.
+func newFromImportPath(importPath string, mode doc.Mode) (Package, error) {
 	// Generate the go/build Package for the import path so we can have more visibility into this
 	// package's structure.
 	buildPkg, err := build.Import(importPath, "", 0)
-	if err != nil {
+	if err != nil && buildPkg.Dir == "" {
+		return Package{}, fmt.Errorf("%w: %s: %v", ErrNotFound, importPath, err)
+	} else if err != nil {
 		return Package{}, fmt.Errorf("build error: invalid package in %s: %w", importPath, err)
 	}
 
@@ -75,46 +118,181 @@ func New(importPath string) (Package, error) {
 		return Package{}, fmt.Errorf("invalid package in %s: %w", importPath, err)
 	}
 
+	return parsePackage(buildPkg, astPkgs, fset, importPath, mode, nil)
+}
+
+// NewFromDir parses the package in the directory dir and creates a new Package object with its
+// information. This is useful for packages that are not reachable through an import path, like a
+// checkout somewhere on disk or a temporary directory of generated code.
+func NewFromDir(dir string) (Package, error) {
+	return newFromDir(dir, 0)
+}
+
+// newFromDir does the work for NewFromDir. mode is passed to go/doc and controls which declarations
+// are included.
+func newFromDir(dir string, mode doc.Mode) (Package, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return Package{}, fmt.Errorf("invalid directory %s: %w", dir, err)
+	}
+	if _, err := os.Stat(absDir); errors.Is(err, fs.ErrNotExist) {
+		return Package{}, fmt.Errorf("%w: %s", ErrNotFound, dir)
+	}
+
+	buildPkg, err := build.ImportDir(absDir, 0)
+	if err != nil {
+		return Package{}, fmt.Errorf("build error: invalid package in %s: %w", dir, err)
+	}
+
+	fset := token.NewFileSet()
+	astPkgs, err := parser.ParseDir(fset, buildPkg.Dir, nil, parser.ParseComments)
+	if err != nil {
+		return Package{}, fmt.Errorf("invalid package in %s: %w", dir, err)
+	}
+
+	// go/build can only determine the import path for directories inside GOROOT or GOPATH.
+	importPath := buildPkg.ImportPath
+	if importPath == "." {
+		importPath = ""
+	}
+
+	return parsePackage(buildPkg, astPkgs, fset, importPath, mode, nil)
+}
+
+// NewFromSources parses the package made up of the in-memory source files in sources and creates a
+// new Package object with its information. Each key in the map is a file name (like "foo.go" or
+// "foo_test.go") and each value is the contents of that file. Because the files do not exist on
+// disk, the package has no import path and no subdirectories.
+func NewFromSources(sources map[string][]byte) (Package, error) {
+	return newFromSources(sources, 0)
+}
+
+// newFromSources does the work for NewFromSources. mode is passed to go/doc and controls which
+// declarations are included.
+func newFromSources(sources map[string][]byte, mode doc.Mode) (Package, error) {
+	// Run the files through the same go/build logic as a package on disk so that build constraints
+	// and test files are classified the same way.
+	buildPkg, err := sourcesContext(sources).ImportDir(sourcesDir, 0)
+	if err != nil {
+		return Package{}, fmt.Errorf("build error: invalid package in sources: %w", err)
+	}
+	buildPkg.Dir = ""
+
+	fset := token.NewFileSet()
+	astPkgs, err := parseSources(fset, sources)
+	if err != nil {
+		return Package{}, fmt.Errorf("invalid package in sources: %w", err)
+	}
+
+	return parsePackage(buildPkg, astPkgs, fset, "", mode, nil)
+}
+
+// parsePackage builds a Package object out of the parsed files in astPkgs for the package described
+// by buildPkg. importPath is used for documentation and error reporting only. mode is passed to go/doc
+// and controls which declarations are included. imp imports the package's dependencies for type
+// checking, or is nil to use the shared importer for the standard library.
+func parsePackage(buildPkg *build.Package, astPkgs map[string]*ast.Package, fset *token.FileSet, importPath string, mode doc.Mode, imp types.Importer) (Package, error) {
+	location := importPath
+	if location == "" {
+		location = buildPkg.Name
+	}
+
 	// Get the go/ast Package for the package named by the import path.
 	astPkg, ok := astPkgs[buildPkg.Name]
 	if !ok {
-		return Package{}, fmt.Errorf("package not found in %s", importPath)
+		return Package{}, fmt.Errorf("package not found in %s", location)
 	}
 	if astPkg == nil {
-		return Package{}, fmt.Errorf("missing package %s in %s", importPath, buildPkg.Dir)
+		return Package{}, fmt.Errorf("missing package %s in %s", location, buildPkg.Dir)
 	}
 
 	// Generate the go/doc Package for the package named by the import path. We first have to
 	// flatten out the map of ast files and then use that list to parse the individual files. We
-	// want to gather all files for the package and not filter out any based on build systems.
-	astFiles := make([]*ast.File, 0, len(astPkg.Files))
-	for _, v := range astPkg.Files {
-		astFiles = append(astFiles, v)
+	// want to gather all files for the package and not filter out any based on build systems. The
+	// files are sorted by name so that the results do not depend on map ordering.
+	fileNames := make([]string, 0, len(astPkg.Files))
+	for name := range astPkg.Files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+	astFiles := make([]*ast.File, 0, len(fileNames))
+	goFiles := make(map[string]*ast.File)
+	var testFiles []*ast.File
+	for _, name := range fileNames {
+		astFiles = append(astFiles, astPkg.Files[name])
+		if strings.HasSuffix(name, "_test.go") {
+			testFiles = append(testFiles, astPkg.Files[name])
+		} else {
+			goFiles[name] = astPkg.Files[name]
+		}
+	}
+
+	// go/doc finds examples in the test files of both this package and its external test package.
+	if xtestPkg, ok := astPkgs[buildPkg.Name+"_test"]; ok {
+		xtestNames := make([]string, 0, len(xtestPkg.Files))
+		for name := range xtestPkg.Files {
+			xtestNames = append(xtestNames, name)
+		}
+		sort.Strings(xtestNames)
+		for _, name := range xtestNames {
+			astFiles = append(astFiles, xtestPkg.Files[name])
+			testFiles = append(testFiles, xtestPkg.Files[name])
+		}
+	}
+
+	// Type-check the package and find the build constraints before go/doc filters out the unexported
+	// declarations and removes the comments.
+	ti := typeCheck(buildPkg, astPkg, fset, importPath, imp)
+	constraints := newBuildConstraints(goFiles)
+
+	// go/doc strips the bodies of functions, but we need them to find where each function ends, so
+	// we'll put them back afterward.
+	bodies := make(map[*ast.FuncDecl]*ast.BlockStmt)
+	for _, astFile := range astFiles {
+		for _, decl := range astFile.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				bodies[fd] = fd.Body
+			}
+		}
 	}
-	docPkg, err := doc.NewFromFiles(fset, astFiles, importPath)
+
+	docPkg, err := doc.NewFromFiles(fset, astFiles, importPath, mode)
 	if err != nil {
-		return Package{}, fmt.Errorf("invalid package in %s: %w", importPath, err)
+		return Package{}, fmt.Errorf("invalid package in %s: %w", location, err)
+	}
+	for fd, body := range bodies {
+		fd.Body = body
 	}
 	if docPkg == nil {
 		return Package{}, ErrInvalidPkg
 	}
 
 	// Put everything together into our Package type.
-	return newPackage(buildPkg, docPkg, fset)
+	pkg, err := newPackage(buildPkg, docPkg, testFiles, fset, ti, mode)
+	if err != nil {
+		return Package{}, err
+	}
+	constraints.apply(&pkg)
+
+	return pkg, nil
 }
 
-// newPackage puts together the internal structure for a Package object.
-func newPackage(buildPkg *build.Package, docPkg *doc.Package, fset *token.FileSet) (Package, error) {
+// newPackage puts together the internal structure for a Package object. testFiles are the parsed test
+// files for the package and its external test package. mode is the go/doc mode that docPkg was
+// created with.
+func newPackage(buildPkg *build.Package, docPkg *doc.Package, testFiles []*ast.File, fset *token.FileSet, ti *typeInfo, mode doc.Mode) (Package, error) {
 	// Begin with structuring up our object with what we have so far.
 	pkg := Package{
 		name:       docPkg.Name,
 		importPath: docPkg.ImportPath,
 		comments:   docPkg.Doc,
+		dir:        buildPkg.Dir,
 	}
 
-	// Put together the list of source files, both for this system's build and those ignored for this
-	// system's build. We have to manually exclude test files because they might be in the same package.
-	for _, ss := range [][]string{buildPkg.GoFiles, buildPkg.IgnoredGoFiles} {
+	// Put together the list of source files, both for this system's build (including files that use
+	// cgo) and those ignored for this system's build. We have to manually exclude test files because
+	// they might be in the same package.
+	for _, ss := range [][]string{buildPkg.GoFiles, buildPkg.CgoFiles, buildPkg.IgnoredGoFiles} {
 		for _, s := range ss {
 			if !strings.HasSuffix(s, "_test.go") {
 				pkg.files = append(pkg.files, s)
@@ -137,14 +315,17 @@ func newPackage(buildPkg *build.Package, docPkg *doc.Package, fset *token.FileSe
 	}
 	sort.Strings(pkg.testFiles)
 
-	// Find all the subdirectories within this package's directory.
-	subs, _ := os.ReadDir(buildPkg.Dir)
-	for _, sub := range subs {
-		if sub.IsDir() {
-			pkg.subdirectories = append(pkg.subdirectories, sub.Name())
+	// Find all the subdirectories within this package's directory. Packages loaded from in-memory
+	// sources have no directory.
+	if buildPkg.Dir != "" {
+		subs, _ := os.ReadDir(buildPkg.Dir)
+		for _, sub := range subs {
+			if sub.IsDir() {
+				pkg.subdirectories = append(pkg.subdirectories, sub.Name())
+			}
 		}
+		sort.Strings(pkg.subdirectories)
 	}
-	sort.Strings(pkg.subdirectories)
 
 	// Copy the list of imports from the source files.
 	pkg.imports = append([]string{}, buildPkg.Imports...)
@@ -166,36 +347,41 @@ func newPackage(buildPkg *build.Package, docPkg *doc.Package, fset *token.FileSe
 	// Extract the blocks of exported constants for this package, both for standard types (go/doc's
 	// Consts) and for custom types (go/doc's Type's Consts).
 	for _, cb := range docPkg.Consts {
-		pkg.constantBlocks = append(pkg.constantBlocks, newConstantBlock(cb, nil, fset))
+		pkg.constantBlocks = append(pkg.constantBlocks, newConstantBlock(cb, nil, fset, ti))
 	}
 	for _, t := range docPkg.Types {
 		for _, cb := range t.Consts {
-			pkg.constantBlocks = append(pkg.constantBlocks, newConstantBlock(cb, t, fset))
+			pkg.constantBlocks = append(pkg.constantBlocks, newConstantBlock(cb, t, fset, ti))
 		}
 	}
 
 	// Extract the blocks of exported variables for this package, both for standard types (go/doc's
 	// Vars) and for custom types (go/doc's Type's Vars).
 	for _, vb := range docPkg.Vars {
-		pkg.variableBlocks = append(pkg.variableBlocks, newVariableBlock(vb, nil, fset))
+		pkg.variableBlocks = append(pkg.variableBlocks, newVariableBlock(vb, nil, fset, ti))
 	}
 	for _, t := range docPkg.Types {
 		for _, vb := range t.Vars {
-			pkg.variableBlocks = append(pkg.variableBlocks, newVariableBlock(vb, t, fset))
+			pkg.variableBlocks = append(pkg.variableBlocks, newVariableBlock(vb, t, fset, ti))
 		}
 	}
 
 	// Extract the exported functions for this package.
 	pkg.functions = make([]Function, len(docPkg.Funcs))
 	for i, f := range docPkg.Funcs {
-		pkg.functions[i] = newFunction(f, fset)
+		pkg.functions[i] = newFunction(f, fset, ti)
 	}
 
 	// Extract the exported types for this package.
 	pkg.types = make([]Type, len(docPkg.Types))
 	for i, t := range docPkg.Types {
-		pkg.types[i] = newType(t, fset)
+		pkg.types[i] = newType(t, fset, ti)
 	}
+	resolveMethodSets(pkg.types, ti, mode&doc.AllDecls != 0)
+
+	// Extract the tests, benchmarks, fuzz targets, and examples from the test files.
+	pkg.tests, pkg.benchmarks, pkg.fuzzTargets = newTestFunctions(testFiles, fset)
+	attachExamples(&pkg, docPkg, testFiles, fset)
 
 	return pkg, nil
 }
@@ -210,6 +396,31 @@ func (p Package) ImportPath() string {
 	return p.importPath
 }
 
+// ModulePath returns the path of the module that contains the package, or "" if the package does
+// not belong to a module or was not loaded in module mode. See Config's Load.
+func (p Package) ModulePath() string {
+	return p.modulePath
+}
+
+// ModuleVersion returns the version of the module that contains the package, like "v1.2.3". For a
+// module replaced by a local directory, this is the version that was replaced, as required by the main
+// module. This is "" for the main module and for packages that were not loaded in module mode.
+func (p Package) ModuleVersion() string {
+	return p.moduleVersion
+}
+
+// ModuleRoot returns the absolute path to the root directory of the module that contains the
+// package, or "" if the package does not belong to a module or was not loaded in module mode.
+func (p Package) ModuleRoot() string {
+	return p.moduleRoot
+}
+
+// Dir returns the absolute path to the directory that contains the package's source files, or "" if
+// the package was loaded from in-memory sources.
+func (p Package) Dir() string {
+	return p.dir
+}
+
 // Comments returns the general package overview documentation with pkg's formatting applied.
 func (p Package) Comments(width int) string {
 	return formatComments(p.comments, width)
@@ -219,7 +430,8 @@ func (p Package) Comments(width int) string {
 // directory, not absolute on the filesystem. Test files (*_test.go) are not included in the list.
 // To get a list of test files in the package, see Package's TestFiles. Note: This returns all
 // source files in the package's directory and does not limit the files based on what is actually
-// used when building for the current system.
+// used when building for the current system. To limit the files to a single system, see Package's
+// For.
 func (p Package) Files() []string {
 	return append([]string{}, p.files...)
 }
@@ -257,7 +469,7 @@ func (p Package) TestImports() []string {
 // blocks of a standard type (like int or string) and blocks of a custom type (like io.Reader or
 // *http.Client). In the latter case, ConstantBlock's Type method can be used to determine the
 // block's general type. The list includes only blocks of exported constants from the source files,
-// not the test files.
+// not the test files, unless the package was loaded with Config's AllDecls.
 func (p Package) ConstantBlocks() []ConstantBlock {
 	return append([]ConstantBlock{}, p.constantBlocks...)
 }
@@ -266,18 +478,59 @@ func (p Package) ConstantBlocks() []ConstantBlock {
 // blocks of a standard type (like int or string) and blocks of a custom type (like io.Reader or
 // *http.Client). In the latter case, VariableBlock's Type method can be used to determine the
 // block's general type. The list includes only blocks of exported variables from the source files,
-// not the test files.
+// not the test files, unless the package was loaded with Config's AllDecls.
 func (p Package) VariableBlocks() []VariableBlock {
 	return append([]VariableBlock{}, p.variableBlocks...)
 }
 
 // Functions returns a list of exported functions in the package. The list includes exported
-// functions from source files for the package only, not from test files (internal or external).
+// functions from source files for the package only, not from test files (internal or external). If
+// the package was loaded with Config's AllDecls, unexported functions are included as well.
 func (p Package) Functions() []Function {
 	return append([]Function{}, p.functions...)
 }
 
-// Types returns a list of exported types in the package.
+// Types returns a list of exported types in the package. If the package was loaded with Config's
+// AllDecls, unexported types (and the unexported fields and methods of every type) are included as
+// well.
 func (p Package) Types() []Type {
 	return append([]Type{}, p.types...)
 }
+
+// ErrorTypes returns a list of exported types in the package that implement the error interface,
+// meaning types with an Error() string method on either a value or pointer receiver and interfaces
+// that include that method.
+func (p Package) ErrorTypes() []Type {
+	types := make([]Type, 0)
+	for _, t := range p.types {
+		if t.isError() {
+			types = append(types, t)
+		}
+	}
+
+	return types
+}
+
+// Tests returns a list of the tests in the package's test files, like "func TestX(t *testing.T)".
+func (p Package) Tests() []TestFunction {
+	return append([]TestFunction{}, p.tests...)
+}
+
+// Benchmarks returns a list of the benchmarks in the package's test files, like
+// "func BenchmarkX(b *testing.B)".
+func (p Package) Benchmarks() []TestFunction {
+	return append([]TestFunction{}, p.benchmarks...)
+}
+
+// FuzzTargets returns a list of the fuzz targets in the package's test files, like
+// "func FuzzX(f *testing.F)".
+func (p Package) FuzzTargets() []TestFunction {
+	return append([]TestFunction{}, p.fuzzTargets...)
+}
+
+// Examples returns a list of every example in the package's test files, sorted by name. This includes
+// the examples for the package itself and for each of its functions, types, and methods. Examples that
+// go/doc cannot associate with an exported declaration are not included.
+func (p Package) Examples() []Example {
+	return append([]Example{}, p.examples...)
+}
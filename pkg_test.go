@@ -21,7 +21,15 @@
 package pkg_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -666,6 +674,15 @@ func TestReadOnlyType(t *testing.T) {
 		}
 
 		for _, typeT := range p.Types() {
+			if fields := typeT.Fields(); len(fields) > 0 {
+				fields[0] = pkg.Field{}
+				if fields := typeT.Fields(); reflect.DeepEqual(fields[0], pkg.Field{}) {
+					t.Error("Type's Fields method is not read-only")
+
+					continue
+				}
+			}
+
 			if functions := typeT.Functions(); len(functions) > 0 {
 				functions[0] = pkg.Function{}
 				if functions := typeT.Functions(); reflect.DeepEqual(functions[0], pkg.Function{}) {
@@ -730,3 +747,3001 @@ func TestReadOnlyParameter(t *testing.T) {
 
 	// No-op: Parameter currently does not return any types that could be modified.
 }
+
+// testSources is a small in-memory package used to test loading packages from outside of an import
+// path.
+var testSources = map[string][]byte{
+	"shape.go": []byte(`// Package shape describes simple shapes.
+package shape
+
+import "math"
+
+// Circle is a round shape.
+type Circle struct {
+	Radius float64
+}
+
+// NewCircle creates a new Circle with radius r.
+func NewCircle(r float64) *Circle {
+	return &Circle{Radius: r}
+}
+
+// Area returns the area of the circle.
+func (c Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+`),
+	"shape_windows.go": []byte(`package shape
+
+// Windows is only built on Windows.
+func Windows() {}
+`),
+	"shape_test.go": []byte(`package shape_test
+
+import "testing"
+
+func TestCircle(t *testing.T) {}
+`),
+}
+
+// TestNewFromSources checks that a package can be created from in-memory source files.
+func TestNewFromSources(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(testSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Name() != "shape" {
+		t.Errorf("incorrect package name (want shape, have %s)", p.Name())
+	}
+	if p.ImportPath() != "" {
+		t.Errorf("incorrect import path (want \"\", have %s)", p.ImportPath())
+	}
+	if want, have := "Package shape describes simple shapes.\n", p.Comments(0); want != have {
+		t.Errorf("incorrect package comments (want %q, have %q)", want, have)
+	}
+	if err := cmpStringLists([]string{"shape.go", "shape_windows.go"}, p.Files()); err != nil {
+		t.Errorf("source files: %s", err.Error())
+	}
+	if err := cmpStringLists([]string{"shape_test.go"}, p.TestFiles()); err != nil {
+		t.Errorf("test files: %s", err.Error())
+	}
+	if err := cmpStringLists([]string{"math"}, p.Imports()); err != nil {
+		t.Errorf("imports: %s", err.Error())
+	}
+	if err := cmpStringLists([]string{"testing"}, p.TestImports()); err != nil {
+		t.Errorf("test imports: %s", err.Error())
+	}
+	if len(p.Subdirectories()) != 0 {
+		t.Errorf("in-memory package has subdirectories: %v", p.Subdirectories())
+	}
+
+	functions := p.Functions()
+	if len(functions) != 1 || functions[0].Name() != "Windows" {
+		t.Errorf("incorrect functions: %v", functions)
+	}
+
+	types := p.Types()
+	if len(types) != 1 || types[0].Name() != "Circle" {
+		t.Fatalf("incorrect types: %v", types)
+	}
+	if fs := types[0].Functions(); len(fs) != 1 || fs[0].Name() != "NewCircle" {
+		t.Errorf("incorrect functions for Circle: %v", fs)
+	}
+	if ms := types[0].Methods(); len(ms) != 1 || ms[0].Name() != "Area" {
+		t.Errorf("incorrect methods for Circle: %v", ms)
+	}
+
+	// A set of sources without any Go files is not a package.
+	if _, err := pkg.NewFromSources(map[string][]byte{"README": []byte("hello")}); err == nil {
+		t.Error("no error for sources without Go files")
+	}
+}
+
+// TestNewFromDir checks that a package can be created from a directory on disk.
+func TestNewFromDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for name, src := range testSources {
+		if err := os.WriteFile(filepath.Join(dir, name), src, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "internal"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := pkg.NewFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Name() != "shape" {
+		t.Errorf("incorrect package name (want shape, have %s)", p.Name())
+	}
+	if p.Dir() != dir {
+		t.Errorf("incorrect directory (want %s, have %s)", dir, p.Dir())
+	}
+	if err := cmpStringLists([]string{"shape.go", "shape_windows.go"}, p.Files()); err != nil {
+		t.Errorf("source files: %s", err.Error())
+	}
+	if err := cmpStringLists([]string{"internal"}, p.Subdirectories()); err != nil {
+		t.Errorf("subdirectories: %s", err.Error())
+	}
+
+	// The results should be the same as for the in-memory package.
+	q, err := pkg.NewFromSources(testSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Types(), q.Types()) {
+		t.Error("types differ between directory and in-memory packages")
+	}
+	if !reflect.DeepEqual(p.Functions(), q.Functions()) {
+		t.Error("functions differ between directory and in-memory packages")
+	}
+
+	if _, err := pkg.NewFromDir(filepath.Join(dir, "missing")); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("incorrect error for missing directory (want %v, have %v)", pkg.ErrNotFound, err)
+	}
+}
+
+// TestConfigLoad checks that packages can be loaded in module mode, including through replace
+// directives.
+func TestConfigLoad(t *testing.T) {
+	t.Parallel()
+
+	// Load this package from its own module.
+	p, err := pkg.Config{}.Load("github.com/snhilde/pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "pkg" || p.ImportPath() != "github.com/snhilde/pkg" {
+		t.Errorf("incorrect package (want pkg/github.com/snhilde/pkg, have %s/%s)", p.Name(), p.ImportPath())
+	}
+	if p.ModulePath() != "github.com/snhilde/pkg" || p.ModuleVersion() != "" || p.ModuleRoot() != wd {
+		t.Errorf("incorrect module (want github.com/snhilde/pkg, \"\", %s, have %s, %q, %s)",
+			wd, p.ModulePath(), p.ModuleVersion(), p.ModuleRoot())
+	}
+
+	// Set up a module that replaces one of its requirements with a local directory.
+	root := t.TempDir()
+	files := map[string]string{
+		"app/go.mod": "module example.com/app\n\ngo 1.21\n\nrequire example.com/lib v1.2.3\n\nreplace example.com/lib v1.2.3 => ../lib\n",
+		"app/app.go": "package app\n\nimport \"example.com/lib\"\n\n// Greet says hello.\nvar Greet = lib.Hello\n",
+		"lib/go.mod": "module example.com/lib\n\ngo 1.21\n",
+		"lib/lib.go": "// Package lib is a library.\npackage lib\n\n// Hello says hello.\nfunc Hello() {}\n",
+		"lib/tag.go": "//go:build special\n\npackage lib\n\n// Special is only built with the special tag.\nfunc Special() {}\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := pkg.Config{Dir: filepath.Join(root, "app"), Env: append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")}
+	p, err = config.Load("example.com/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ImportPath() != "example.com/lib" || p.Comments(0) != "Package lib is a library.\n" {
+		t.Errorf("incorrect package (have %s: %q)", p.ImportPath(), p.Comments(0))
+	}
+	if p.ModulePath() != "example.com/lib" || p.ModuleVersion() != "v1.2.3" || p.ModuleRoot() != filepath.Join(root, "lib") {
+		t.Errorf("incorrect module (have %s, %s, %s)", p.ModulePath(), p.ModuleVersion(), p.ModuleRoot())
+	}
+	if err := cmpStringLists([]string{"lib.go", "tag.go"}, p.Files()); err != nil {
+		t.Errorf("source files: %s", err.Error())
+	}
+
+	// Directories relative to the working directory should also resolve.
+	config.Dir = filepath.Join(root, "lib")
+	if p, err = config.Load("."); err != nil {
+		t.Error(err)
+	} else if p.ImportPath() != "example.com/lib" {
+		t.Errorf("incorrect import path (want example.com/lib, have %s)", p.ImportPath())
+	}
+
+	// Packages that are not provided by any module should not be found.
+	if _, err := config.Load("example.com/missing"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("incorrect error for missing package (want %v, have %v)", pkg.ErrNotFound, err)
+	}
+
+	// Dependencies outside the standard library should be type-checked from the config's module.
+	config.Dir = filepath.Join(root, "app")
+	app, err := config.Load("example.com/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocks := app.VariableBlocks(); len(blocks) != 1 || blocks[0].Variables()[0].Type() != "func()" {
+		t.Errorf("dependency not type-checked: %v", blocks)
+	}
+
+	// The caller's environment should not be changed, even if it has room to grow.
+	env := make([]string, len(config.Env), len(config.Env)+1)
+	copy(env, config.Env)
+	config.Env = env
+	if _, err := config.Load("example.com/lib"); err != nil {
+		t.Error(err)
+	}
+	if extra := env[:cap(env)][len(env)]; extra != "" {
+		t.Errorf("environment changed (have %q)", extra)
+	}
+}
+
+// TestTypeFields checks that the fields of struct types are extracted correctly.
+func TestTypeFields(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"fields.go": []byte(`package fields
+
+import "io"
+
+// Record has many kinds of fields.
+type Record struct {
+	// ID is the record's identifier.
+	ID int ` + "`json:\"id\" xml:\"id,attr\"`" + `
+	First, Last string // The person's name.
+	io.Reader
+	*Inner
+	hidden bool
+	Raw []byte ` + "`json:\"-\" malformed`" + `
+}
+
+// Inner is embedded in Record.
+type Inner struct{}
+
+// Number is not a struct.
+type Number int
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := p.Types()
+	if len(types) != 3 {
+		t.Fatalf("incorrect number of types (want 3, have %v)", len(types))
+	}
+
+	type testField struct {
+		name        string
+		typeName    string
+		tag         string
+		tagKeys     []string
+		comments    string
+		lineComment string
+		embedded    bool
+	}
+	want := []testField{
+		{"ID", "int", `json:"id" xml:"id,attr"`, []string{"json", "xml"}, "ID is the record's identifier.\n", "", false},
+		{"First", "string", "", []string{}, "", "The person's name.", false},
+		{"Last", "string", "", []string{}, "", "The person's name.", false},
+		{"Reader", "io.Reader", "", []string{}, "", "", true},
+		{"Inner", "*Inner", "", []string{}, "", "", true},
+		{"Raw", "[]byte", `json:"-" malformed`, []string{"json"}, "", "", false},
+	}
+
+	have := types[2].Fields()
+	if types[2].Name() != "Record" || len(have) != len(want) {
+		t.Fatalf("incorrect fields for %s (want %v, have %v)", types[2].Name(), len(want), len(have))
+	}
+	for i, w := range want {
+		h := have[i]
+		if w.name != h.Name() || w.typeName != h.Type() || w.tag != h.Tag() || w.embedded != h.Embedded() {
+			t.Errorf("field %v: mismatch (want %s %s %q %v, have %s %s %q %v)",
+				i, w.name, w.typeName, w.tag, w.embedded, h.Name(), h.Type(), h.Tag(), h.Embedded())
+		}
+		if err := cmpStringLists(w.tagKeys, h.TagKeys()); err != nil {
+			t.Errorf("%s: tag keys: %s", w.name, err.Error())
+		}
+		if w.comments != h.Comments(0) || w.lineComment != h.LineComment() {
+			t.Errorf("%s: comments mismatch (want %q %q, have %q %q)", w.name, w.comments, w.lineComment, h.Comments(0), h.LineComment())
+		}
+		if !h.Exported() {
+			t.Errorf("%s: field is not exported", w.name)
+		}
+	}
+
+	if tags := have[0].Tags(); tags["json"] != "id" || tags["xml"] != "id,attr" {
+		t.Errorf("incorrect tags for ID: %v", tags)
+	}
+
+	if fields := types[0].Fields(); len(fields) != 0 {
+		t.Errorf("empty struct has fields: %v", fields)
+	}
+	if fields := types[1].Fields(); len(fields) != 0 {
+		t.Errorf("non-struct type has fields: %v", fields)
+	}
+}
+
+// TestInterfaceMethods checks that the methods of interface types are extracted and flattened
+// correctly.
+func TestInterfaceMethods(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"iface.go": []byte(`package iface
+
+import "io"
+
+// Closer closes things.
+type Closer interface {
+	// Close closes the thing.
+	Close() error
+}
+
+// ReadCloser reads and closes things.
+type ReadCloser interface {
+	io.Reader
+	Closer
+
+	// Name returns the name of the thing.
+	Name() (name string)
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := p.Types()
+	if len(types) != 2 || types[1].Name() != "ReadCloser" {
+		t.Fatalf("incorrect types: %v", types)
+	}
+	rc := types[1]
+
+	methods := rc.InterfaceMethods()
+	if len(methods) != 1 || methods[0].Name() != "Name" {
+		t.Fatalf("incorrect interface methods: %v", methods)
+	}
+	if methods[0].Comments(0) != "Name returns the name of the thing.\n" {
+		t.Errorf("incorrect comments for Name: %q", methods[0].Comments(0))
+	}
+	if err := cmpParameterLists([]testParameter{{"name string", "name", "string", false}}, methods[0].Outputs()); err != nil {
+		t.Errorf("Name: outputs: %s", err.Error())
+	}
+
+	if err := cmpStringLists([]string{"io.Reader", "Closer"}, rc.EmbeddedInterfaces()); err != nil {
+		t.Errorf("embedded interfaces: %s", err.Error())
+	}
+
+	// The method set should include methods from the same package (with comments) and from
+	// imported packages (through type information).
+	set := rc.InterfaceMethodSet()
+	names := make([]string, len(set))
+	for i, m := range set {
+		names[i] = m.Name()
+	}
+	if err := cmpStringLists([]string{"Close", "Name", "Read"}, names); err != nil {
+		t.Fatalf("method set: %s", err.Error())
+	}
+	if set[0].Comments(0) != "Close closes the thing.\n" || set[0].Receiver().Type() != "Closer" {
+		t.Errorf("incorrect Close method: %q, %s", set[0].Comments(0), set[0].Receiver().Type())
+	}
+	if err := cmpParameterLists([]testParameter{{"p []byte", "p", "[]byte", false}}, set[2].Inputs()); err != nil {
+		t.Errorf("Read: inputs: %s", err.Error())
+	}
+	if err := cmpParameterLists([]testParameter{{"n int", "n", "int", false}, {"err error", "err", "error", false}}, set[2].Outputs()); err != nil {
+		t.Errorf("Read: outputs: %s", err.Error())
+	}
+	if set[2].Receiver().Type() != "io.Reader" {
+		t.Errorf("incorrect receiver for Read: %s", set[2].Receiver().Type())
+	}
+
+	// Non-interface types have no interface methods.
+	q, err := pkg.NewFromSources(testSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range q.Types() {
+		if len(typ.InterfaceMethods()) != 0 || len(typ.EmbeddedInterfaces()) != 0 || len(typ.InterfaceMethodSet()) != 0 {
+			t.Errorf("%s: non-interface type has interface methods", typ.Name())
+		}
+	}
+}
+
+// findFunction returns the function with the given name in p, or fails the test if there isn't one.
+func findFunction(t *testing.T, p pkg.Package, name string) pkg.Function {
+	t.Helper()
+
+	for _, f := range p.Functions() {
+		if f.Name() == name {
+			return f
+		}
+	}
+	t.Fatalf("%s: function %s not found", p.ImportPath(), name)
+
+	return pkg.Function{}
+}
+
+// cmpTypeParams checks that a list of type parameters matches the wanted names, constraints, and
+// terms. Each wanted item is in the form "name constraint: term term ...".
+func cmpTypeParams(want []string, have []pkg.TypeParam) error {
+	list := make([]string, len(have))
+	for i, tp := range have {
+		terms := make([]string, len(tp.Terms()))
+		for j, term := range tp.Terms() {
+			terms[j] = term.String()
+		}
+		list[i] = strings.TrimSpace(tp.String() + ": " + strings.Join(terms, " "))
+	}
+
+	return cmpStringLists(want, list)
+}
+
+// TestTypeParams checks that type parameters are extracted correctly for generic types, functions,
+// and methods, including generic packages in the standard library.
+func TestTypeParams(t *testing.T) {
+	t.Parallel()
+
+	slices, err := pkg.New("slices")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The terms of named constraints are resolved through type information.
+	sortFunc := findFunction(t, slices, "Sort")
+	ordered := "~int ~int8 ~int16 ~int32 ~int64 ~uint ~uint8 ~uint16 ~uint32 ~uint64 ~uintptr ~float32 ~float64 ~string"
+	if err := cmpTypeParams([]string{"S ~[]E: ~[]E", "E cmp.Ordered: " + ordered}, sortFunc.TypeParams()); err != nil {
+		t.Errorf("slices.Sort: type parameters: %s", err.Error())
+	}
+
+	insert := findFunction(t, slices, "Insert")
+	if err := cmpTypeParams([]string{"S ~[]E: ~[]E", "E any:"}, insert.TypeParams()); err != nil {
+		t.Errorf("slices.Insert: type parameters: %s", err.Error())
+	}
+	if err := cmpParameterLists([]testParameter{
+		{"s S", "s", "S", false}, {"i int", "i", "int", false}, {"v ...E", "v", "...E", false},
+	}, insert.Inputs()); err != nil {
+		t.Errorf("slices.Insert: inputs: %s", err.Error())
+	}
+
+	maps, err := pkg.New("maps")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp := findFunction(t, maps, "Copy")
+	if err := cmpTypeParams([]string{"M1 ~map[K]V: ~map[K]V", "M2 ~map[K]V: ~map[K]V", "K comparable:", "V any:"}, cp.TypeParams()); err != nil {
+		t.Errorf("maps.Copy: type parameters: %s", err.Error())
+	}
+
+	keys := findFunction(t, maps, "Keys")
+	if outputs := keys.Outputs(); len(outputs) != 1 {
+		t.Errorf("maps.Keys: incorrect number of outputs: %v", len(outputs))
+	} else if err := cmpStringLists([]string{"K"}, outputs[0].TypeArgs()); err != nil {
+		t.Errorf("maps.Keys: output type arguments: %s", err.Error())
+	}
+
+	// Check generic types and methods.
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"list.go": []byte(`package list
+
+// Number is a constraint for numbers.
+type Number interface {
+	~int | ~float64
+}
+
+// Pair holds two values.
+type Pair[K comparable, V interface{ ~string | []byte }] struct {
+	Key   K
+	Value V
+}
+
+// List is a list of numbers.
+type List[T Number] struct {
+	items []T
+}
+
+// Push adds v to the list.
+func (l *List[E]) Push(v E) {}
+
+// Sum adds up the list.
+func (l List[_]) Sum() float64 { return 0 }
+
+// Zero returns the zero value.
+func Zero[T int | uint]() T {
+	var t T
+	return t
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := p.Types()
+	if len(types) != 3 {
+		t.Fatalf("incorrect number of types (want 3, have %v)", len(types))
+	}
+	if err := cmpTypeParams([]string{"T Number: ~int ~float64"}, types[0].TypeParams()); err != nil {
+		t.Errorf("List: type parameters: %s", err.Error())
+	}
+	if tps := types[1].TypeParams(); len(tps) != 0 {
+		t.Errorf("Number: non-generic type has type parameters: %v", tps)
+	}
+	if err := cmpTypeParams([]string{"K comparable:", "V interface{ ~string | []byte }: ~string []byte"}, types[2].TypeParams()); err != nil {
+		t.Errorf("Pair: type parameters: %s", err.Error())
+	}
+
+	methods := types[0].Methods()
+	if len(methods) != 2 {
+		t.Fatalf("List: incorrect number of methods (want 2, have %v)", len(methods))
+	}
+	if err := cmpTypeParams([]string{"E Number: ~int ~float64"}, methods[0].TypeParams()); err != nil {
+		t.Errorf("List.Push: type parameters: %s", err.Error())
+	}
+	if err := cmpStringLists([]string{"E"}, methods[0].Receiver().TypeArgs()); err != nil {
+		t.Errorf("List.Push: receiver type arguments: %s", err.Error())
+	}
+	if !methods[0].PointerReceiver() || methods[0].Receiver().Type() != "*List[E]" {
+		t.Errorf("List.Push: incorrect receiver: %s", methods[0].Receiver())
+	}
+	if err := cmpTypeParams([]string{"_ Number: ~int ~float64"}, methods[1].TypeParams()); err != nil {
+		t.Errorf("List.Sum: type parameters: %s", err.Error())
+	}
+
+	zero := findFunction(t, p, "Zero")
+	if err := cmpTypeParams([]string{"T int | uint: int uint"}, zero.TypeParams()); err != nil {
+		t.Errorf("Zero: type parameters: %s", err.Error())
+	}
+}
+
+// TestConstantValues checks that the types, values, and comments of constants are resolved
+// correctly, including iota and implicit repetition.
+func TestConstantValues(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"consts.go": []byte(`package consts
+
+import "time"
+
+// Weekday is a day of the week.
+type Weekday int
+
+// Prune is not a rune.
+type Prune int
+
+// Days of the week.
+const (
+	// Sunday is the first day.
+	Sunday Weekday = iota
+	Monday // The second day.
+	Tuesday
+	_
+	Thursday
+)
+
+// Assorted constants.
+const (
+	Greeting         = "hello"
+	Big              = 1 << 70
+	Pi       float64 = 3.25
+	Timeout          = 5 * time.Second
+	Letter           = 'A' + 2
+	Truth            = Big > 0
+	Kilo, Mega       = 1e3, Kilo * Kilo
+	Answer, secret   = 42, 7
+	Initial  rune    = 'x'
+	Trimmed  Prune   = 65
+)
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := p.ConstantBlocks()
+	if len(blocks) != 2 {
+		t.Fatalf("incorrect number of constant blocks (want 2, have %v)", len(blocks))
+	}
+
+	type testValue struct {
+		name     string
+		typeName string
+		value    string
+		comments string
+	}
+	tests := [][]testValue{
+		{
+			{"Greeting", "untyped string", `"hello"`, ""},
+			{"Big", "untyped int", "1180591620717411303424", ""},
+			{"Pi", "float64", "3.25", ""},
+			{"Timeout", "time.Duration", "5000000000", ""},
+			{"Letter", "untyped rune", "'C'", ""},
+			{"Truth", "untyped bool", "true", ""},
+			{"Kilo", "untyped float", "1000", ""},
+			{"Mega", "untyped float", "1e+06", ""},
+			{"Answer", "untyped int", "42", ""},
+			{"Initial", "rune", "'x'", ""},
+			{"Trimmed", "Prune", "65", ""},
+		},
+		{
+			{"Sunday", "Weekday", "0", "Sunday is the first day.\n"},
+			{"Monday", "Weekday", "1", "The second day.\n"},
+			{"Tuesday", "Weekday", "2", ""},
+			{"Thursday", "Weekday", "4", ""},
+		},
+	}
+
+	for i, want := range tests {
+		have := blocks[i].Constants()
+		if len(want) != len(have) {
+			t.Errorf("block %v: incorrect number of constants (want %v, have %v)", i, len(want), len(have))
+
+			continue
+		}
+		for j, w := range want {
+			h := have[j]
+			if w.name != h.Name() || w.typeName != h.Type() || w.value != h.Value() || w.comments != h.Comments(0) {
+				t.Errorf("block %v: constant mismatch (want %s %s %s %q, have %s %s %s %q)",
+					i, w.name, w.typeName, w.value, w.comments, h.Name(), h.Type(), h.Value(), h.Comments(0))
+			}
+			if h.ExactValue() == nil {
+				t.Errorf("block %v: %s: missing exact value", i, h.Name())
+			}
+		}
+	}
+
+	// Check a well-known constant from the standard library.
+	unicode, err := pkg.New("unicode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range unicode.ConstantBlocks() {
+		for _, c := range block.Constants() {
+			if c.Name() == "MaxRune" {
+				if c.Value() != `'\U0010ffff'` || c.Type() != "untyped rune" {
+					t.Errorf("unicode.MaxRune: incorrect value (want '\\U0010ffff' untyped rune, have %s %s)", c.Value(), c.Type())
+				}
+				if v, ok := constant.Int64Val(c.ExactValue()); !ok || v != 0x10FFFF {
+					t.Errorf("unicode.MaxRune: incorrect exact value: %v", c.ExactValue())
+				}
+
+				return
+			}
+		}
+	}
+	t.Error("unicode.MaxRune not found")
+}
+
+// TestVariableDetails checks that the types, initializers, comments, and positions of variables are
+// extracted correctly.
+func TestVariableDetails(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"vars.go": []byte(`package vars
+
+import (
+	"errors"
+	"strings"
+)
+
+// Assorted variables.
+var (
+	// Count is declared with a type.
+	Count int
+	Name = "vars" // Name is inferred.
+	Lower, hidden, Upper = strings.ToLower, 1, strings.ToUpper
+	First, Second = split()
+)
+
+var ErrBad = errors.New("bad")
+
+func split() (int, string) { return 0, "" }
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := p.VariableBlocks()
+	if len(blocks) != 2 {
+		t.Fatalf("incorrect number of variable blocks (want 2, have %v)", len(blocks))
+	}
+
+	type testVariable struct {
+		name        string
+		typeName    string
+		initializer string
+		comments    string
+		position    string
+		endLine     int
+	}
+	want := []testVariable{
+		{"Count", "int", "", "Count is declared with a type.\n", "vars.go:11:2", 11},
+		{"Name", "string", `"vars"`, "Name is inferred.\n", "vars.go:12:2", 12},
+		{"Lower", "func(s string) string", "strings.ToLower", "", "vars.go:13:2", 13},
+		{"Upper", "func(s string) string", "strings.ToUpper", "", "vars.go:13:17", 13},
+		{"First", "int", "split()", "", "vars.go:14:2", 14},
+		{"Second", "string", "split()", "", "vars.go:14:9", 14},
+	}
+	have := blocks[0].Variables()
+	if len(want) != len(have) {
+		t.Fatalf("incorrect number of variables (want %v, have %v)", len(want), len(have))
+	}
+	for i, w := range want {
+		h := have[i]
+		if w.name != h.Name() || w.typeName != h.Type() || w.initializer != h.Initializer() || w.comments != h.Comments(0) {
+			t.Errorf("variable mismatch (want %s %s %s %q, have %s %s %s %q)",
+				w.name, w.typeName, w.initializer, w.comments, h.Name(), h.Type(), h.Initializer(), h.Comments(0))
+		}
+		if w.position != h.Position().String() || w.endLine != h.Position().EndLine() {
+			t.Errorf("%s: position mismatch (want %s-%v, have %s-%v)", w.name, w.position, w.endLine, h.Position(), h.Position().EndLine())
+		}
+	}
+
+	errVar := blocks[1].Variables()
+	if len(errVar) != 1 || errVar[0].Type() != "error" || errVar[0].Initializer() != `errors.New("bad")` {
+		t.Errorf("incorrect error variable: %v", errVar)
+	}
+}
+
+// TestErrors checks that exported errors and error types are found through type checking.
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"errs.go": []byte(`package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const prefix = "errs: "
+
+var (
+	ErrPlain   = errors.New("plain")
+	ErrFormat  = fmt.Errorf("format %d", 1)
+	ErrConst   = errors.New(prefix + "const")
+	Failure    = io.EOF
+	ErrTyped   error
+	ErrCustom  = &CustomError{}
+	ErrNotErr  = "not an error"
+	errHidden  = errors.New("hidden")
+	Dynamic    = errors.New(fmt.Sprint("dynamic"))
+)
+
+// CustomError is an error type with a pointer receiver.
+type CustomError struct{}
+
+// Error returns the error message.
+func (e *CustomError) Error() string { return "custom" }
+
+// ValueError is an error type with a value receiver.
+type ValueError int
+
+// Error returns the error message.
+func (e ValueError) Error() string { return "value" }
+
+// Temporary is an interface that includes error.
+type Temporary interface {
+	error
+	Temporary() bool
+}
+
+// NotError has an Error method with the wrong signature.
+type NotError struct{}
+
+// Error does not return a string.
+func (NotError) Error() int { return 0 }
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := p.VariableBlocks()
+	if len(blocks) != 1 {
+		t.Fatalf("incorrect number of variable blocks (want 1, have %v)", len(blocks))
+	}
+
+	want := []string{
+		"ErrPlain: plain", "ErrFormat: format %d", "ErrConst: errs: const", "Failure:", "ErrTyped:",
+		"ErrCustom:", "Dynamic:",
+	}
+	errs := blocks[0].Errors()
+	have := make([]string, len(errs))
+	for i, e := range errs {
+		have[i] = strings.TrimSpace(e.Name() + ": " + e.Message())
+	}
+	if err := cmpStringLists(want, have); err != nil {
+		t.Errorf("errors: %s", err.Error())
+	}
+
+	errorTypes := p.ErrorTypes()
+	names := make([]string, len(errorTypes))
+	for i, et := range errorTypes {
+		names[i] = et.Name()
+	}
+	if err := cmpStringLists([]string{"CustomError", "Temporary", "ValueError"}, names); err != nil {
+		t.Errorf("error types: %s", err.Error())
+	}
+}
+
+// TestPositions checks that every declaration records its location in the source files.
+func TestPositions(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"a.go": []byte(`package positions
+
+// Answer is the answer.
+const Answer = 42
+
+// Grouped types.
+type (
+	// Point is a point.
+	Point struct {
+		X, Y int
+	}
+
+	// ID is an identifier.
+	ID string
+)
+`),
+		"b.go": []byte(`package positions
+
+import "errors"
+
+var ErrNope = errors.New("nope")
+
+// Distance returns the distance between a and b.
+func Distance(a, b Point) (d int) {
+	return a.X - b.X
+}
+
+// String returns the ID as a string.
+func (id ID) String() string {
+	return string(id)
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		what     string
+		position pkg.Position
+		want     string
+		endLine  int
+	}{
+		{"constant block", p.ConstantBlocks()[0].Position(), "a.go:4:1", 4},
+		{"constant", p.ConstantBlocks()[0].Constants()[0].Position(), "a.go:4:7", 4},
+		{"variable block", p.VariableBlocks()[0].Position(), "b.go:5:1", 5},
+		{"variable", p.VariableBlocks()[0].Variables()[0].Position(), "b.go:5:5", 5},
+		{"error", p.VariableBlocks()[0].Errors()[0].Position(), "b.go:5:5", 5},
+		{"type in group", p.Types()[1].Position(), "a.go:9:2", 11},
+		{"field", p.Types()[1].Fields()[1].Position(), "a.go:10:6", 10},
+		{"function", p.Functions()[0].Position(), "b.go:8:1", 10},
+		{"input", p.Functions()[0].Inputs()[1].Position(), "b.go:8:18", 8},
+		{"output", p.Functions()[0].Outputs()[0].Position(), "b.go:8:28", 8},
+		{"method", p.Types()[0].Methods()[0].Position(), "b.go:13:1", 15},
+		{"receiver", p.Types()[0].Methods()[0].Receiver().Position(), "b.go:13:7", 13},
+	}
+	for _, test := range tests {
+		if test.want != test.position.String() || test.endLine != test.position.EndLine() {
+			t.Errorf("%s: incorrect position (want %s-%v, have %s-%v)", test.what, test.want, test.endLine,
+				test.position, test.position.EndLine())
+		}
+		if test.position.File() == "" || !test.position.IsValid() {
+			t.Errorf("%s: invalid position", test.what)
+		}
+	}
+
+	// Types declared on their own begin at the "type" keyword.
+	q, err := pkg.NewFromSources(testSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := q.Types()[0].Position(); have.String() != "shape.go:7:1" || have.EndLine() != 9 || have.EndColumn() != 2 {
+		t.Errorf("incorrect position for Circle (want shape.go:7:1-9:2, have %s-%v:%v)", have, have.EndLine(), have.EndColumn())
+	}
+
+	if (pkg.Position{}).IsValid() || (pkg.Position{}).String() != "-" {
+		t.Error("zero position is valid")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"round.go": []byte(`// Package round is used to test JSON encoding.
+package round
+
+import "errors"
+
+// Assorted constants.
+const (
+	Name    = "round"
+	Third   = 1.0 / 3
+	Big     = 1 << 100
+	Imag    = 2 + 3i
+	Enabled = true
+)
+
+// ErrRound is returned for round things.
+var ErrRound = errors.New("round")
+
+// Pair holds two values.
+type Pair[K comparable, V ~int | ~string] struct {
+	Key   K ` + "`json:\"key\" xml:\"k\"`" + ` // The key.
+	Value V
+}
+
+// Swap swaps the values in the pair.
+func (p *Pair[K, V]) Swap() {}
+
+// Sizer can report a size.
+type Sizer interface {
+	Size() int
+}
+
+// NewPair creates a new pair.
+func NewPair[K comparable, V ~int | ~string](k K, v V) Pair[K, V] {
+	return Pair[K, V]{k, v}
+}
+`),
+		"round_test.go": []byte(`package round
+
+import "testing"
+
+// TestSwap tests swapping.
+func TestSwap(t *testing.T) {}
+
+func ExamplePair_Swap() {
+	// Output: swapped
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var q pkg.Package
+	if err := json.Unmarshal(b, &q); err != nil {
+		t.Fatal(err)
+	}
+
+	// Encoding the decoded package must produce exactly the same JSON.
+	c, err := json.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("JSON does not round-trip:\nwant %s\nhave %s", b, c)
+	}
+
+	if q.Name() != "round" || q.Comments(0) != p.Comments(0) {
+		t.Errorf("incorrect package (want %s, have %s)", p.Name(), q.Name())
+	}
+
+	// Constant values must survive without any loss of precision.
+	want := p.ConstantBlocks()[0].Constants()
+	have := q.ConstantBlocks()[0].Constants()
+	if len(want) != len(have) {
+		t.Fatalf("incorrect number of constants (want %v, have %v)", len(want), len(have))
+	}
+	for i := range want {
+		w, h := want[i].ExactValue(), have[i].ExactValue()
+		if w.Kind() != h.Kind() || !constant.Compare(w, token.EQL, h) {
+			t.Errorf("%s: incorrect value (want %v, have %v)", want[i].Name(), w, h)
+		}
+		if want[i].Type() != have[i].Type() || want[i].Position() != have[i].Position() {
+			t.Errorf("%s: incorrect type or position", want[i].Name())
+		}
+	}
+
+	if errs := q.VariableBlocks()[0].Errors(); len(errs) != 1 || errs[0].Message() != "round" {
+		t.Errorf("incorrect errors: %v", errs)
+	}
+
+	pair := q.Types()[0]
+	if pair.Name() != "Pair" || len(pair.TypeParams()) != 2 || pair.Methods()[0].Receiver().Type() != "*Pair[K, V]" {
+		t.Errorf("incorrect type: %s", pair.Name())
+	}
+	if terms := pair.TypeParams()[1].Terms(); len(terms) != 2 || !terms[0].Tilde() || terms[1].Type() != "string" {
+		t.Errorf("incorrect type terms: %v", terms)
+	}
+	if key := pair.Fields()[0]; key.Tags()["xml"] != "k" || key.LineComment() != "The key." {
+		t.Errorf("incorrect field: %s", key.Name())
+	}
+	if pos := pair.Position(); pos != p.Types()[0].Position() || !pos.IsValid() {
+		t.Errorf("incorrect position (want %s, have %s)", p.Types()[0].Position(), pos)
+	}
+	if have := q.Types()[1].InterfaceMethods(); len(have) != 1 || have[0].Name() != "Size" {
+		t.Errorf("incorrect interface methods: %v", have)
+	}
+
+	if tests := q.Tests(); len(tests) != 1 || tests[0].Comments(0) != "TestSwap tests swapping.\n" {
+		t.Errorf("incorrect tests: %v", tests)
+	}
+	if examples := pair.Methods()[0].Examples(); len(examples) != 1 || examples[0].Output() != "swapped\n" {
+		t.Errorf("incorrect examples: %v", examples)
+	}
+
+	// JSON from an unknown version of the format must be rejected.
+	if err := json.Unmarshal([]byte(`{"version": 99, "name": "round"}`), &q); !errors.Is(err, pkg.ErrUnsupportedVersion) {
+		t.Errorf("unsupported version not rejected: %v", err)
+	}
+
+	if !json.Valid(pkg.JSONSchema()) {
+		t.Error("invalid JSON schema")
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	schema := pkg.JSONSchema()
+	dups, err := duplicateKeys(json.NewDecoder(bytes.NewReader(schema)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dups) > 0 {
+		t.Errorf("duplicate keys in JSON schema: %v", dups)
+	}
+
+	// Declare one of everything under a build constraint so that every key in the format is filled in.
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"all.go": []byte(`//go:build go1.1
+
+// Package all has one of everything.
+package all
+
+import (
+	"errors"
+	"io"
+)
+
+// Limits.
+const (
+	// Max is the most.
+	Max int = 10
+)
+
+// Errors and settings.
+var (
+	// ErrAll is returned for everything.
+	ErrAll = errors.New("all")
+
+	// Verbose turns on logging.
+	Verbose = false
+)
+
+// Set holds values.
+type Set[T ~int | string] struct {
+	io.Reader
+	Items []T ` + "`json:\"items\"`" + ` // The values.
+}
+
+// NewSet creates a set.
+func NewSet[T ~int | string](items ...T) *Set[T] { return nil }
+
+// Len returns the number of values.
+func (s *Set[T]) Len() (n int) { return 0 }
+
+// ReadCloser reads and closes.
+type ReadCloser interface {
+	io.Reader
+	Close() error
+	closed() bool
+}
+
+// Sum adds the values.
+func Sum(values []int) int { return 0 }
+`),
+		"all_test.go": []byte(`package all
+
+import "testing"
+
+// TestSum tests Sum.
+func TestSum(t *testing.T) {}
+
+// BenchmarkSum times Sum.
+func BenchmarkSum(b *testing.B) {}
+
+// FuzzSum fuzzes Sum.
+func FuzzSum(f *testing.F) {}
+
+// ExampleSum shows Sum.
+func ExampleSum() {
+	// Unordered output: 0
+}
+
+func Example_second() {
+	// Output: 1
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var root map[string]any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		t.Fatal(err)
+	}
+	var value any
+	if err := json.Unmarshal(b, &value); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every key in the package's JSON must be a property in the schema.
+	defs, _ := root["$defs"].(map[string]any)
+	for _, key := range missingKeys(root, value, defs, "") {
+		t.Errorf("%s: key not in JSON schema", key)
+	}
+}
+
+// duplicateKeys returns the keys that are repeated within any object in the next value from dec.
+func duplicateKeys(dec *json.Decoder) ([]string, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	var dups []string
+	switch token {
+	case json.Delim('{'):
+		seen := make(map[string]bool)
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := token.(string)
+			if seen[key] {
+				dups = append(dups, key)
+			}
+			seen[key] = true
+
+			d, err := duplicateKeys(dec)
+			if err != nil {
+				return nil, err
+			}
+			dups = append(dups, d...)
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		for dec.More() {
+			d, err := duplicateKeys(dec)
+			if err != nil {
+				return nil, err
+			}
+			dups = append(dups, d...)
+		}
+		_, err = dec.Token()
+	}
+
+	return dups, err
+}
+
+// missingKeys returns the path to every key in value that is not a property of the schema node.
+// References to the schema's definitions in defs are followed.
+func missingKeys(node map[string]any, value any, defs map[string]any, path string) []string {
+	if ref, ok := node["$ref"].(string); ok {
+		def, _ := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		return missingKeys(def, value, defs, path)
+	}
+
+	var missing []string
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := node["properties"].(map[string]any)
+		extra, _ := node["additionalProperties"].(map[string]any)
+		for key, elem := range v {
+			prop, ok := props[key].(map[string]any)
+			if !ok {
+				prop = extra
+			}
+			if prop == nil {
+				missing = append(missing, path+"/"+key)
+				continue
+			}
+			missing = append(missing, missingKeys(prop, elem, defs, path+"/"+key)...)
+		}
+	case []any:
+		items, _ := node["items"].(map[string]any)
+		for i, elem := range v {
+			missing = append(missing, missingKeys(items, elem, defs, fmt.Sprintf("%s/%v", path, i))...)
+		}
+	}
+
+	return missing
+}
+
+func TestFunctionSource(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(testSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	circle := p.Types()[0]
+	tests := []struct {
+		want string
+		have string
+	}{
+		{"func Windows()", p.Functions()[0].Source()},
+		{"func NewCircle(r float64) *Circle", circle.Functions()[0].Source()},
+		{"func (c Circle) Area() float64", circle.Methods()[0].Source()},
+	}
+	for _, test := range tests {
+		if test.want != test.have {
+			t.Errorf("incorrect source (want %q, have %q)", test.want, test.have)
+		}
+	}
+
+	q, err := pkg.NewFromSources(map[string][]byte{
+		"sizer.go": []byte(`package sizer
+
+import "fmt"
+
+// Sizer can report a size.
+type Sizer interface {
+	fmt.Stringer
+
+	// Size returns the size.
+	Size() (n int, err error)
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sizer := q.Types()[0]
+	if want, have := "Size() (n int, err error)", sizer.InterfaceMethods()[0].Source(); want != have {
+		t.Errorf("incorrect source for interface method (want %q, have %q)", want, have)
+	}
+	for _, m := range sizer.InterfaceMethodSet() {
+		if m.Name() == "String" && m.Source() != "String() string" {
+			t.Errorf("incorrect source for embedded method (want %q, have %q)", "String() string", m.Source())
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(testSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sb := new(strings.Builder)
+	if err := pkg.RenderMarkdown(sb, p, pkg.MarkdownOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything must be rendered in order.
+	want := []string{
+		"# <a id=\"pkg-overview\"></a>Package shape\n\nPackage shape describes simple shapes.\n",
+		"## <a id=\"pkg-index\"></a>Index\n",
+		"- [func Windows()](#Windows)\n- [type Circle](#Circle)\n",
+		"  - [func NewCircle(r float64) \\*Circle](#NewCircle)\n",
+		"  - [func (c Circle) Area() float64](#Circle.Area)\n",
+		"## <a id=\"pkg-functions\"></a>Functions\n",
+		"### <a id=\"Windows\"></a>func Windows\n\n```go\nfunc Windows()\n```\n\nWindows is only built on Windows.\n",
+		"## <a id=\"pkg-types\"></a>Types\n",
+		"### <a id=\"Circle\"></a>type Circle\n\n```go\ntype Circle struct {\n\tRadius float64\n}\n```\n\nCircle is a round shape.\n",
+		"#### <a id=\"NewCircle\"></a>func NewCircle\n",
+		"#### <a id=\"Circle.Area\"></a>func (c Circle) Area\n\n```go\nfunc (c Circle) Area() float64\n```\n",
+	}
+	have := sb.String()
+	for _, s := range want {
+		i := strings.Index(have, s)
+		if i < 0 {
+			t.Fatalf("missing %q in:\n%s", s, sb.String())
+		}
+		have = have[i+len(s):]
+	}
+	if strings.Contains(sb.String(), "pkg-constants") || strings.Contains(sb.String(), "pkg-variables") {
+		t.Error("empty sections rendered")
+	}
+
+	// Only the selected sections are rendered, starting at the requested heading level.
+	sb.Reset()
+	opts := pkg.MarkdownOptions{HeadingLevel: 3, Sections: pkg.SectionTypes}
+	if err := pkg.RenderMarkdown(sb, p, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sb.String(), "#### <a id=\"pkg-types\"></a>Types\n") {
+		t.Errorf("incorrect first heading:\n%s", sb.String())
+	}
+	if !strings.Contains(sb.String(), "###### <a id=\"Circle.Area\"></a>") {
+		t.Errorf("incorrect method heading:\n%s", sb.String())
+	}
+	if strings.Contains(sb.String(), "Package shape") || strings.Contains(sb.String(), "Windows") {
+		t.Errorf("unselected sections rendered:\n%s", sb.String())
+	}
+
+	// Links in documentation point to anchors in the same document.
+	q, err := pkg.NewFromSources(map[string][]byte{
+		"link.go": []byte(`// Package link uses [Thing] and [Thing.Do] and [ErrLink].
+package link
+
+import "errors"
+
+// ErrLink is a link error.
+var ErrLink = errors.New("link")
+
+// Thing does things.
+type Thing struct{}
+
+// Do does the thing.
+func (Thing) Do() {}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb.Reset()
+	if err := pkg.RenderMarkdown(sb, q, pkg.MarkdownOptions{Sections: pkg.SectionOverview | pkg.SectionVariables}); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"[Thing](#Thing)", "[Thing.Do](#Thing.Do)", "[ErrLink](#ErrLink)", "<a id=\"ErrLink\"></a>"} {
+		if !strings.Contains(sb.String(), s) {
+			t.Errorf("missing %q in:\n%s", s, sb.String())
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"buffer.go": []byte(`// Package buffer holds [Buffer] and uses [io.Reader].
+package buffer
+
+import (
+	"io"
+	"strings"
+)
+
+// Tag is written around buffers.
+const Tag = "<b>" // Bold.
+
+// Buffer holds data.
+type Buffer struct {
+	r      io.Reader
+	Buffer *Buffer
+}
+
+// NewBuffer creates a new Buffer that reads from r.
+func NewBuffer(r io.Reader, sb strings.Builder) *Buffer {
+	return &Buffer{r: r}
+}
+
+// Copy copies n bytes into b.
+func Copy(b *Buffer, n int) error {
+	return nil
+}
+
+// Fill fills a buffer.
+func Fill(io io.Reader, Buffer int) (strings int) {
+	return 0
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sb := new(strings.Builder)
+	if err := pkg.RenderHTML(sb, p, pkg.HTMLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	have := sb.String()
+	want := []string{
+		"<!DOCTYPE html>",
+		"<title>buffer - Go Documentation</title>",
+		"<h1 id=\"pkg-overview\">Package buffer</h1>",
+		"<a href=\"#Buffer\">Buffer</a> and uses <a href=\"https://pkg.go.dev/io#Reader\">io.Reader</a>",
+		"<details id=\"pkg-index\" open>\n<summary><h2>Index</h2></summary>",
+		"<li><a href=\"#Copy\">func Copy(b *Buffer, n int) error</a></li>",
+		"<details id=\"pkg-constants\" open>",
+		"<span id=\"Tag\"></span>",
+		"<span class=\"keyword\">const</span> Tag = <span class=\"literal\">&#34;&lt;b&gt;&#34;</span> <span class=\"comment\">// Bold.</span>",
+		"<section id=\"Copy\">\n<h3>func Copy</h3>",
+		"Copy(b *<a href=\"#Buffer\">Buffer</a>, n int) error",
+		"Fill(io <a href=\"https://pkg.go.dev/io\">io</a>.<a href=\"https://pkg.go.dev/io#Reader\">Reader</a>, Buffer int) (strings int)",
+		"<section id=\"Buffer\">\n<h3>type Buffer</h3>",
+		"Buffer *<a href=\"#Buffer\">Buffer</a>",
+		"r <a href=\"https://pkg.go.dev/io\">io</a>.<a href=\"https://pkg.go.dev/io#Reader\">Reader</a>",
+		"sb <a href=\"https://pkg.go.dev/strings\">strings</a>.<a href=\"https://pkg.go.dev/strings#Builder\">Builder</a>) *<a href=\"#Buffer\">Buffer</a>",
+		"</main>\n</body>\n</html>\n",
+	}
+	for _, s := range want {
+		i := strings.Index(have, s)
+		if i < 0 {
+			t.Fatalf("missing %q in:\n%s", s, sb.String())
+		}
+		have = have[i+len(s):]
+	}
+
+	// Imported packages link to the given URLs, and only the selected sections are rendered.
+	sb.Reset()
+	opts := pkg.HTMLOptions{
+		Sections:   pkg.SectionTypes,
+		PackageURL: func(importPath string) string { return "/pkg/" + importPath },
+	}
+	if err := pkg.RenderHTML(sb, p, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), "<a href=\"/pkg/io#Reader\">Reader</a>") {
+		t.Errorf("incorrect package URL:\n%s", sb.String())
+	}
+	if strings.Contains(sb.String(), "pkg-overview") || strings.Contains(sb.String(), "pkg-functions") {
+		t.Errorf("unselected sections rendered:\n%s", sb.String())
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"text.go": []byte(`// Package text is used to test rendering documentation as text.
+package text
+
+// Assorted sizes.
+const (
+	Small  = 1 // The smallest size.
+	Medium = 2
+)
+
+// Default is the default size.
+var Default = Medium
+
+// Box holds things.
+type Box[T any] struct {
+	Items []T
+}
+
+// NewBox creates a new, empty Box. This sentence makes the comment long enough to wrap.
+func NewBox[T any]() *Box[T] {
+	return &Box[T]{}
+}
+
+// Add adds an item to the box.
+func (b *Box[T]) Add(item T) {}
+
+// Len returns the number of items in the box.
+func (b *Box[T]) Len() int {
+	return len(b.Items)
+}
+
+// Open opens a box.
+func Open() {}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		opts pkg.TextOptions
+		want string
+	}{
+		{pkg.TextOptions{}, `package text
+
+Package text is used to test rendering documentation as text.
+
+const Small = 1 ...
+var Default = Medium
+func Open()
+type Box[T any] struct{ ... }
+    func NewBox[T any]() *Box[T]
+`},
+		{pkg.TextOptions{Short: true, All: true}, `const Small = 1 ...
+var Default = Medium
+func Open()
+type Box[T any] struct{ ... }
+`},
+		{pkg.TextOptions{All: true, Width: 60}, `package text
+
+Package text is used to test rendering documentation as
+text.
+
+CONSTANTS
+
+const (
+	Small  = 1 // The smallest size.
+	Medium = 2
+)
+    Assorted sizes.
+
+
+VARIABLES
+
+var Default = Medium
+    Default is the default size.
+
+
+FUNCTIONS
+
+func Open()
+    Open opens a box.
+
+
+TYPES
+
+type Box[T any] struct {
+	Items []T
+}
+    Box holds things.
+
+func NewBox[T any]() *Box[T]
+    NewBox creates a new, empty Box. This sentence makes the
+    comment long enough to wrap.
+
+func (b *Box[T]) Add(item T)
+    Add adds an item to the box.
+
+func (b *Box[T]) Len() int
+    Len returns the number of items in the box.
+
+`},
+	}
+	for _, test := range tests {
+		sb := new(strings.Builder)
+		if err := pkg.RenderText(sb, p, test.opts); err != nil {
+			t.Fatal(err)
+		}
+		if test.want != sb.String() {
+			t.Errorf("%+v: incorrect text\nwant:\n%s\nhave:\n%s", test.opts, test.want, sb.String())
+		}
+	}
+
+	box := p.Types()[0]
+	sb := new(strings.Builder)
+	if err := pkg.RenderTypeText(sb, box, pkg.TextOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "type Box[T any] struct {\n\tItems []T\n}\n    Box holds things.\n\n" +
+		"func NewBox[T any]() *Box[T]\nfunc (b *Box[T]) Add(item T)\nfunc (b *Box[T]) Len() int\n"
+	if want != sb.String() {
+		t.Errorf("incorrect text for type\nwant:\n%s\nhave:\n%s", want, sb.String())
+	}
+
+	sb.Reset()
+	if err := pkg.RenderFunctionText(sb, p.Functions()[0], pkg.TextOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "func Open()\n    Open opens a box.\n\n"; want != sb.String() {
+		t.Errorf("incorrect text for function (want %q, have %q)", want, sb.String())
+	}
+
+	sb.Reset()
+	if err := pkg.RenderMethodText(sb, box.Methods()[1], pkg.TextOptions{Short: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "func (b *Box[T]) Len() int\n"; want != sb.String() {
+		t.Errorf("incorrect text for method (want %q, have %q)", want, sb.String())
+	}
+}
+
+func TestRenderMan(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"man.go": []byte(`// Package man is used to test rendering man pages.
+//
+// Paths use backslashes, like C:\Go.
+//
+//	.nroff requests in code must be escaped
+//
+// Features:
+//   - sections
+//   - escaping
+package man
+
+// Version is the version.
+const Version = "1.0"
+
+// Page is a man page.
+type Page struct {
+	Title string
+}
+
+// NewPage creates a new Page.
+func NewPage() *Page {
+	return nil
+}
+
+// Render renders the page.
+func (p *Page) Render() string {
+	return ""
+}
+
+// Install installs a page.
+func Install(p *Page) error {
+	return nil
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sb := new(strings.Builder)
+	if err := pkg.RenderMan(sb, p, pkg.ManOptions{Date: "2024-01-01"}); err != nil {
+		t.Fatal(err)
+	}
+
+	have := sb.String()
+	want := []string{
+		".TH \"MAN\" \"3\" \"2024-01-01\" \"\" \"Go Packages\"\n",
+		".SH NAME\nman \\- is used to test rendering man pages.\n",
+		".SH SYNOPSIS\n.nf\nconst Version = \"1.0\"\nfunc Install(p *Page) error\ntype Page struct{ ... }\n" +
+			"    func NewPage() *Page\n    func (p *Page) Render() string\n.fi\n",
+		".SH DESCRIPTION\n.PP\nPackage man is used to test rendering man pages.\n",
+		".PP\nPaths use backslashes, like C:\\eGo.\n",
+		".PP\n.RS\n.nf\n\\&.nroff requests in code must be escaped\n.fi\n.RE\n",
+		".IP \\(bu 4\nsections\n.IP \\(bu 4\nescaping\n.PP\n",
+		".SH CONSTANTS\n.PP\n.nf\n\\fBconst Version = \"1.0\"\\fR\n.fi\n.RS\n.PP\nVersion is the version.\n.RE\n",
+		".SH FUNCTIONS\n.SS \"func Install\"\n.PP\n.nf\n\\fBfunc Install(p *Page) error\\fR\n.fi\n",
+		".SH TYPES\n.SS \"type Page\"\n.PP\n.nf\n\\fBtype Page struct {\\fR\n\\fB\tTitle string\\fR\n\\fB}\\fR\n.fi\n",
+		"\\fBfunc NewPage() *Page\\fR\n",
+		"\\fBfunc (p *Page) Render() string\\fR\n.fi\n.RS\n.PP\nRender renders the page.\n.RE\n",
+	}
+	for _, s := range want {
+		i := strings.Index(have, s)
+		if i < 0 {
+			t.Fatalf("missing %q in:\n%s", s, sb.String())
+		}
+		have = have[i+len(s):]
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	oldPkg, err := pkg.NewFromSources(map[string][]byte{
+		"api.go": []byte(`package api
+
+import "errors"
+
+const (
+	Version       = "1.0"
+	Limit   int64 = 10
+	Gone          = true
+)
+
+var ErrClosed = errors.New("closed")
+
+var Default = &Client{}
+
+type Client struct {
+	Name    string
+	Timeout int
+}
+
+func NewClient(name string) *Client { return nil }
+
+func (c *Client) Do(req string) error { return nil }
+
+func (c *Client) Close() {}
+
+type Doer interface {
+	Do(req string) error
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newPkg, err := pkg.NewFromSources(map[string][]byte{
+		"api.go": []byte(`package api
+
+import "errors"
+
+const (
+	Version       = "1.1"
+	Limit   int64 = 10
+)
+
+var ErrClosed = errors.New("client closed")
+
+var ErrTimeout = errors.New("timeout")
+
+var Default = &Client{}
+
+type Client struct {
+	Name    string
+	Timeout int64
+	Retries int
+}
+
+func NewClient(n string) *Client { return nil }
+
+func (c *Client) Do(req string, retries int) error { return nil }
+
+type Doer interface {
+	Do(req string) error
+	Close()
+}
+
+type Option func(*Client)
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := pkg.Diff(oldPkg, newPkg)
+	want := []struct {
+		kind   pkg.ChangeKind
+		object string
+		name   string
+		old    string
+		new    string
+	}{
+		{pkg.Removed, "method", "Client.Close", "func (*Client) Close()", ""},
+		{pkg.Changed, "method", "Client.Do", "func (*Client) Do(string) error", "func (*Client) Do(string, int) error"},
+		{pkg.Added, "field", "Client.Retries", "", "Client.Retries int"},
+		{pkg.Changed, "field", "Client.Timeout", "Client.Timeout int", "Client.Timeout int64"},
+		{pkg.Added, "method", "Doer.Close", "", "Doer.Close()"},
+		{pkg.Changed, "error", "ErrClosed", `var ErrClosed error // "closed"`, `var ErrClosed error // "client closed"`},
+		{pkg.Added, "error", "ErrTimeout", "", `var ErrTimeout error // "timeout"`},
+		{pkg.Removed, "constant", "Gone", "const Gone = true", ""},
+		{pkg.Added, "type", "Option", "", "type Option func(*Client)"},
+		{pkg.Changed, "constant", "Version", `const Version = "1.0"`, `const Version = "1.1"`},
+	}
+
+	have := report.Changes()
+	if len(want) != len(have) {
+		t.Fatalf("incorrect number of changes (want %v, have %v):\n%s", len(want), len(have), report)
+	}
+	for i, w := range want {
+		h := have[i]
+		if w.kind != h.Kind() || w.object != h.Object() || w.name != h.Name() || w.old != h.Old() || w.new != h.New() {
+			t.Errorf("incorrect change %v (want %v %s %s %q %q, have %v %s %s %q %q)", i,
+				w.kind, w.object, w.name, w.old, w.new, h.Kind(), h.Object(), h.Name(), h.Old(), h.New())
+		}
+	}
+
+	wantText := `Removed:
+- func (*Client) Close()
+- const Gone = true
+
+Added:
+- Client.Retries int
+- Doer.Close()
+- var ErrTimeout error // "timeout"
+- type Option func(*Client)
+
+Changed:
+- func (*Client) Do(string, int) error
+  was func (*Client) Do(string) error
+- Client.Timeout int64
+  was Client.Timeout int
+- var ErrClosed error // "client closed"
+  was var ErrClosed error // "closed"
+- const Version = "1.1"
+  was const Version = "1.0"
+`
+	if wantText != report.String() {
+		t.Errorf("incorrect text\nwant:\n%s\nhave:\n%s", wantText, report.String())
+	}
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded pkg.Report
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report, decoded) {
+		t.Errorf("report does not round-trip through JSON: %s", b)
+	}
+	if !strings.Contains(string(b), `{"kind":"added","object":"type","name":"Option","new":"type Option func(*Client)","bump":"minor","reason":"added"}`) {
+		t.Errorf("incorrect JSON: %s", b)
+	}
+
+	if !pkg.Diff(oldPkg, oldPkg).Empty() {
+		t.Error("changes found between identical packages")
+	}
+
+	// Methods brought in by embedded interfaces should be compared too, in both directions.
+	embedded, err := pkg.NewFromSources(map[string][]byte{
+		"rc.go": []byte("package rc\n\nimport \"io\"\n\ntype RC interface {\n\tio.Reader\n\tClose() error\n}\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	direct, err := pkg.NewFromSources(map[string][]byte{
+		"rc.go": []byte("package rc\n\ntype RC interface {\n\tClose() error\n}\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		oldPkg pkg.Package
+		newPkg pkg.Package
+		kind   pkg.ChangeKind
+	}{
+		{embedded, direct, pkg.Removed},
+		{direct, embedded, pkg.Added},
+	} {
+		have := pkg.Diff(test.oldPkg, test.newPkg).Changes()
+		if len(have) != 1 || have[0].Kind() != test.kind || have[0].Name() != "RC.Read" || !have[0].Breaking() {
+			t.Errorf("incorrect changes for embedded interface (want %v RC.Read, have %v)", test.kind, have)
+		}
+	}
+}
+
+func TestCompatibility(t *testing.T) {
+	t.Parallel()
+
+	oldPkg, err := pkg.NewFromSources(map[string][]byte{
+		"compat.go": []byte(`package compat
+
+import "errors"
+
+const Size int = 1
+
+const Name = "old"
+
+var ErrOld = errors.New("old")
+
+type Value struct {
+	N int
+}
+
+func (v Value) Get() int { return 0 }
+
+func (v *Value) Set(n int) {}
+
+type Getter interface {
+	Get() int
+}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		desc   string
+		source string
+		bump   pkg.Bump
+		want   map[string]string // Reason for each change, keyed by name.
+	}{
+		{"no changes", `const Size int = 1
+const Name = "old"
+var ErrOld = errors.New("old")
+type Value struct { N int }
+func (v Value) Get() int { return 0 }
+func (v *Value) Set(n int) {}
+type Getter interface { Get() int }`, pkg.Patch, map[string]string{}},
+		{"compatible changes", `const Size int = 1
+const Name = "new"
+var ErrOld = errors.New("new")
+type Value struct { N int }
+func (v Value) Get() int { return 0 }
+func (v Value) Set(n int) {}
+type Getter interface { Get() int }`, pkg.Minor, map[string]string{
+			"ErrOld":    "message changed",
+			"Name":      "value changed",
+			"Value.Set": "receiver changed from pointer to value",
+		}},
+		{"additions", `const Size int = 1
+const Name = "old"
+var ErrOld = errors.New("old")
+type Value struct { N, M int }
+func New() *Value { return nil }
+func (v Value) Get() int { return 0 }
+func (v *Value) Set(n int) {}
+type Getter interface { Get() int }`, pkg.Minor, map[string]string{
+			"New":     "added",
+			"Value.M": "added",
+		}},
+		{"breaking changes", `const Size int64 = 1
+var ErrOld = errors.New("old")
+type Value struct { N string }
+func (v *Value) Get() int { return 0 }
+func (v *Value) Set(n int64) {}
+type Getter interface { Get() int; Reset() }`, pkg.Major, map[string]string{
+			"Getter.Reset": "added to interface",
+			"Name":         "removed",
+			"Size":         "type changed",
+			"Value.Get":    "receiver changed from value to pointer",
+			"Value.N":      "type changed",
+			"Value.Set":    "signature changed",
+		}},
+		{"embedded interfaces", `const Size int = 1
+const Name = "old"
+var ErrOld = errors.New("old")
+type Value struct { N int }
+func (v Value) Get() int { return 0 }
+func (v *Value) Set(n int) {}
+type Getter interface { Get() int; fmt.Stringer }`, pkg.Major, map[string]string{
+			"Getter.String": "added to interface",
+		}},
+		{"unexported interface methods", `const Size int = 1
+const Name = "old"
+var ErrOld = errors.New("old")
+type Value struct { N int }
+func (v Value) Get() int { return 0 }
+func (v *Value) Set(n int) {}
+type Getter interface { Get() int; reset() }`, pkg.Major, map[string]string{
+			"Getter": "unexported methods added to interface",
+		}},
+	}
+	for _, test := range tests {
+		newPkg, err := pkg.NewFromSources(map[string][]byte{
+			"compat.go": []byte("package compat\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n)\n\nvar _ fmt.Stringer\n\n" + test.source + "\n"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := pkg.Diff(oldPkg, newPkg)
+		if test.bump != report.Bump() {
+			t.Errorf("%s: incorrect bump (want %v, have %v):\n%s", test.desc, test.bump, report.Bump(), report)
+		}
+
+		have := make(map[string]string)
+		for _, c := range report.Changes() {
+			have[c.Name()] = c.Reason()
+		}
+		if !reflect.DeepEqual(test.want, have) {
+			t.Errorf("%s: incorrect reasons (want %v, have %v)", test.desc, test.want, have)
+		}
+		if breaking := report.Breaking(); (test.bump == pkg.Major) != (len(breaking) > 0) {
+			t.Errorf("%s: incorrect breaking changes: %v", test.desc, breaking)
+		}
+	}
+
+	// Removing an interface's unexported methods lets other packages implement it.
+	sealed, err := pkg.NewFromSources(map[string][]byte{
+		"compat.go": []byte("package compat\n\ntype Getter interface {\n\tGet() int\n\treset()\n}\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsealed, err := pkg.NewFromSources(map[string][]byte{
+		"compat.go": []byte("package compat\n\ntype Getter interface {\n\tGet() int\n}\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := pkg.Diff(sealed, unsealed); report.Bump() != pkg.Minor {
+		t.Errorf("incorrect bump for removed unexported methods (want %v, have %v):\n%s", pkg.Minor, report.Bump(), report)
+	}
+
+	// Changes with an unknown kind or version bump should not decode.
+	for _, data := range []string{
+		`{"kind":"renamed","object":"function","name":"F","bump":"major"}`,
+		`{"kind":"added","object":"function","name":"F","bump":"huge"}`,
+	} {
+		var c pkg.Change
+		if err := json.Unmarshal([]byte(data), &c); err == nil {
+			t.Errorf("invalid change decoded without error: %s", data)
+		}
+	}
+}
+
+// TestNewTree checks that every package in a tree of directories is loaded, skipping the directories
+// that the go command skips.
+func TestNewTree(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                    "module example.com/tree\n\ngo 1.21\n",
+		"tree.go":                   "// Package tree is the root.\npackage tree\n",
+		"sub/sub.go":                "package sub\n\n// Hello says hello.\nfunc Hello() {}\n",
+		"sub/deeper/deeper.go":      "package deeper\n",
+		"sub/deeper/deeper_test.go": "package deeper_test\n",
+		"docs/README":               "Not a package.\n",
+		"testdata/data.go":          "package data\n",
+		"vendor/example.com/v/v.go": "package v\n",
+		"_skip/skip.go":             "package skip\n",
+		".hidden/hidden.go":         "package hidden\n",
+		"nested/go.mod":             "module example.com/nested\n\ngo 1.21\n",
+		"nested/nested.go":          "package nested\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree, err := pkg.NewTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"example.com/tree", "example.com/tree/sub", "example.com/tree/sub/deeper"}
+	if err := cmpStringLists(want, tree.ImportPaths()); err != nil {
+		t.Errorf("import paths: %s", err.Error())
+	}
+	if tree.Root() != root || tree.Len() != len(want) || len(tree.Packages()) != len(want) {
+		t.Errorf("incorrect tree (want %s with %d packages, have %s with %d)", root, len(want), tree.Root(), tree.Len())
+	}
+
+	p, ok := tree.Package("example.com/tree/sub")
+	if !ok {
+		t.Fatal("missing package example.com/tree/sub")
+	}
+	if p.Name() != "sub" || len(p.Functions()) != 1 || p.ModulePath() != "example.com/tree" || p.ModuleRoot() != root {
+		t.Errorf("incorrect package (have %s with %d functions in %s at %s)", p.Name(), len(p.Functions()), p.ModulePath(), p.ModuleRoot())
+	}
+	if _, ok := tree.Package("example.com/tree/testdata"); ok {
+		t.Error("testdata was loaded")
+	}
+
+	// Patterns without "/..." should match only a single package.
+	var names []string
+	err = pkg.Walk(filepath.Join(root, "sub"), func(p pkg.Package) error {
+		names = append(names, p.ImportPath())
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if err := cmpStringLists([]string{"example.com/tree/sub"}, names); err != nil {
+		t.Errorf("single package: %s", err.Error())
+	}
+
+	// Errors from the callback should stop the walk.
+	stop := errors.New("stop")
+	calls := 0
+	err = pkg.Walk(filepath.Join(root, "..."), func(pkg.Package) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("walk not stopped (want %v after 1 call, have %v after %d)", stop, err, calls)
+	}
+
+	// Packages that fail to load should be reported without stopping the others.
+	broken := filepath.Join(root, "sub", "broken")
+	if err := os.Mkdir(broken, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(broken, "broken.go"), []byte("package broken\n\nfunc {\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tree, err = pkg.NewTree(root)
+	if err == nil {
+		t.Error("no error for broken package")
+	}
+	if tree.Len() != len(want) {
+		t.Errorf("incorrect number of packages (want %d, have %d)", len(want), tree.Len())
+	}
+}
+
+// TestTestFunctions checks that the tests, benchmarks, fuzz targets, and examples in a package's test
+// files are found and that each example is attached to the declaration it documents.
+func TestTestFunctions(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"shape.go": testSources["shape.go"],
+		"shape_test.go": []byte(`package shape
+
+import "testing"
+
+// TestArea checks the area.
+func TestArea(t *testing.T) {}
+
+func TestMain(m *testing.M) {}
+func Testify(t *testing.T)  {}
+func TestHelper(t *testing.T, n int) {}
+
+func BenchmarkArea(b *testing.B) {}
+
+func FuzzArea(f *testing.F) {}
+`),
+		"example_test.go": []byte(`package shape_test
+
+import (
+	"fmt"
+
+	"example.com/shape"
+)
+
+// This example shows the whole package.
+func Example() {
+	fmt.Println("shape")
+	// Output: shape
+}
+
+func ExampleNewCircle() {
+	c := shape.NewCircle(1)
+	fmt.Println(c.Area() > 3) // Should be pi.
+	// Output:
+	// true
+}
+
+func ExampleCircle() {
+	var c shape.Circle
+	_ = c
+}
+
+func ExampleCircle_Area_unit() {
+	for _, r := range []float64{1, 2} {
+		fmt.Println(shape.NewCircle(r).Area() > 0)
+	}
+	// Unordered output:
+	// true
+	// true
+}
+
+func ExampleMissing() {}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(tfs []pkg.TestFunction) []string {
+		s := make([]string, len(tfs))
+		for i, tf := range tfs {
+			s[i] = tf.Name()
+		}
+		return s
+	}
+	if err := cmpStringLists([]string{"TestArea"}, names(p.Tests())); err != nil {
+		t.Errorf("tests: %s", err.Error())
+	}
+	if err := cmpStringLists([]string{"BenchmarkArea"}, names(p.Benchmarks())); err != nil {
+		t.Errorf("benchmarks: %s", err.Error())
+	}
+	if err := cmpStringLists([]string{"FuzzArea"}, names(p.FuzzTargets())); err != nil {
+		t.Errorf("fuzz targets: %s", err.Error())
+	}
+	if test := p.Tests()[0]; test.Comments(0) != "TestArea checks the area.\n" || test.Position().String() != "shape_test.go:6:1" {
+		t.Errorf("incorrect test (have %q at %s)", test.Comments(0), test.Position())
+	}
+
+	examples := p.Examples()
+	want := []struct {
+		name      string
+		symbol    string
+		suffix    string
+		body      string
+		output    string
+		hasOutput bool
+		unordered bool
+	}{
+		{"Example", "", "", `fmt.Println("shape")`, "shape\n", true, false},
+		{"ExampleCircle", "Circle", "", "var c shape.Circle\n_ = c", "", false, false},
+		{"ExampleCircle_Area_unit", "Circle.Area", "unit", "for _, r := range []float64{1, 2} {\n\tfmt.Println(shape.NewCircle(r).Area() > 0)\n}", "true\ntrue\n", true, true},
+		{"ExampleNewCircle", "NewCircle", "", "c := shape.NewCircle(1)\nfmt.Println(c.Area() > 3) // Should be pi.", "true\n", true, false},
+	}
+	if len(examples) != len(want) {
+		t.Fatalf("incorrect number of examples (want %d, have %d)", len(want), len(examples))
+	}
+	for i, w := range want {
+		e := examples[i]
+		if e.Name() != w.name || e.Symbol() != w.symbol || e.Suffix() != w.suffix {
+			t.Errorf("%d: incorrect example (want %s/%s/%s, have %s/%s/%s)", i, w.name, w.symbol, w.suffix, e.Name(), e.Symbol(), e.Suffix())
+		}
+		if e.Body() != w.body {
+			t.Errorf("%s: incorrect body (want %q, have %q)", w.name, w.body, e.Body())
+		}
+		if e.Output() != w.output || e.HasOutput() != w.hasOutput || e.Unordered() != w.unordered {
+			t.Errorf("%s: incorrect output (want %q/%v/%v, have %q/%v/%v)", w.name, w.output, w.hasOutput, w.unordered,
+				e.Output(), e.HasOutput(), e.Unordered())
+		}
+		if e.Position().File() != "example_test.go" {
+			t.Errorf("%s: incorrect position (have %s)", w.name, e.Position())
+		}
+	}
+	if examples[0].Comments(0) != "This example shows the whole package.\n" {
+		t.Errorf("incorrect comments (have %q)", examples[0].Comments(0))
+	}
+
+	// Each example should be attached to the declaration it documents.
+	circle := p.Types()[0]
+	if have := circle.Examples(); len(have) != 1 || have[0].Name() != "ExampleCircle" {
+		t.Errorf("incorrect type examples: %v", have)
+	}
+	if have := circle.Functions()[0].Examples(); len(have) != 1 || have[0].Name() != "ExampleNewCircle" {
+		t.Errorf("incorrect constructor examples: %v", have)
+	}
+	if have := circle.Methods()[0].Examples(); len(have) != 1 || have[0].Name() != "ExampleCircle_Area_unit" {
+		t.Errorf("incorrect method examples: %v", have)
+	}
+}
+
+// TestConstraints checks that the build constraints of files and declarations are found and that a
+// package can be filtered down to the declarations for a single build context.
+func TestConstraints(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{
+		"api.go": []byte(`package api
+
+// All is built everywhere.
+func All() {}
+
+// T is a type.
+type T struct{}
+
+// Common is built everywhere.
+func (T) Common() {}
+`),
+		"api_linux.go": []byte(`package api
+
+// Sep is the separator.
+const Sep = "/"
+
+// LinuxOnly is only built on Linux.
+func LinuxOnly() {}
+
+// Platform is built once for each system.
+func (T) Platform() {}
+`),
+		"api_windows.go": []byte(`package api
+
+// Sep is the separator.
+const Sep = "\\"
+
+// Platform is built once for each system.
+func (T) Platform() {}
+`),
+		"arch_linux_amd64.go": []byte(`package api
+
+// Arch is only built on 64-bit Linux.
+func Arch() {}
+`),
+		"purego.go": []byte(`//go:build purego
+
+package api
+
+// Pure is only built with the purego tag.
+func Pure() {}
+`),
+		"cgo_linux.go": []byte(`//go:build !purego
+
+package api
+
+import "C"
+
+// Cgo is only built with cgo.
+func Cgo() {}
+`),
+		"old.go": []byte(`// +build old
+
+package api
+
+// Old uses the old syntax.
+func Old() {}
+`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := map[string]string{
+		"api_linux.go":        "linux",
+		"api_windows.go":      "windows",
+		"arch_linux_amd64.go": "linux && amd64",
+		"purego.go":           "purego",
+		"cgo_linux.go":        "linux && !purego && cgo",
+		"old.go":              "old",
+	}
+	haveFiles := p.Constraints()
+	if len(haveFiles) != len(wantFiles) {
+		t.Errorf("incorrect number of file constraints (want %d, have %d: %v)", len(wantFiles), len(haveFiles), haveFiles)
+	}
+	for file, want := range wantFiles {
+		if have := haveFiles[file]; have != want {
+			t.Errorf("%s: incorrect constraint (want %q, have %q)", file, want, have)
+		}
+	}
+
+	wantFuncs := map[string]string{
+		"All":       "",
+		"Arch":      "linux && amd64",
+		"Cgo":       "linux && !purego && cgo",
+		"LinuxOnly": "linux",
+		"Old":       "old",
+		"Pure":      "purego",
+	}
+	for _, f := range p.Functions() {
+		if want := wantFuncs[f.Name()]; f.Constraint() != want {
+			t.Errorf("%s: incorrect constraint (want %q, have %q)", f.Name(), want, f.Constraint())
+		}
+	}
+
+	typ := p.Types()[0]
+	if typ.Constraint() != "" {
+		t.Errorf("incorrect type constraint (have %q)", typ.Constraint())
+	}
+	for _, m := range typ.Methods() {
+		want := ""
+		if m.Name() == "Platform" {
+			want = "linux || windows"
+		}
+		if m.Constraint() != want {
+			t.Errorf("%s: incorrect constraint (want %q, have %q)", m.Name(), want, m.Constraint())
+		}
+	}
+	for _, cb := range p.ConstantBlocks() {
+		if c := cb.Constants()[0]; c.Constraint() != cb.Constraint() || (cb.Constraint() != "linux" && cb.Constraint() != "windows") {
+			t.Errorf("incorrect constant constraint (have %q and %q)", cb.Constraint(), c.Constraint())
+		}
+	}
+
+	// Only the declarations for each build context should be visible.
+	tests := []struct {
+		goos   string
+		goarch string
+		tags   []string
+		files  []string
+		funcs  []string
+		sep    string
+	}{
+		{"linux", "amd64", nil, []string{"api.go", "api_linux.go", "arch_linux_amd64.go"}, []string{"All", "Arch", "LinuxOnly"}, `"/"`},
+		{"linux", "arm64", []string{"cgo"}, []string{"api.go", "api_linux.go", "cgo_linux.go"}, []string{"All", "Cgo", "LinuxOnly"}, `"/"`},
+		{"windows", "amd64", []string{"purego", "old"}, []string{"api.go", "api_windows.go", "old.go", "purego.go"}, []string{"All", "Old", "Pure"}, `"\\"`},
+	}
+	for _, test := range tests {
+		name := test.goos + "/" + test.goarch
+		q := p.For(test.goos, test.goarch, test.tags...)
+		if err := cmpStringLists(test.files, q.Files()); err != nil {
+			t.Errorf("%s: files: %s", name, err.Error())
+		}
+
+		funcs := make([]string, 0)
+		for _, f := range q.Functions() {
+			funcs = append(funcs, f.Name())
+		}
+		if err := cmpStringLists(test.funcs, funcs); err != nil {
+			t.Errorf("%s: functions: %s", name, err.Error())
+		}
+
+		if blocks := q.ConstantBlocks(); len(blocks) != 1 || blocks[0].Constants()[0].Value() != test.sep {
+			t.Errorf("%s: incorrect constants: %v", name, blocks)
+		}
+		if methods := q.Types()[0].Methods(); len(methods) != 2 {
+			t.Errorf("%s: incorrect number of methods (want 2, have %d)", name, len(methods))
+		}
+	}
+
+	// The original package should not be changed.
+	if len(p.Functions()) != len(wantFuncs) {
+		t.Errorf("incorrect number of functions (want %d, have %d)", len(wantFuncs), len(p.Functions()))
+	}
+
+	// Constraints should survive encoding to JSON.
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q pkg.Package
+	if err := json.Unmarshal(b, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Constraints()["old.go"] != "old" || q.Types()[0].Methods()[1].Constraint() != "linux || windows" {
+		t.Errorf("constraints not decoded: %v", q.Constraints())
+	}
+}
+
+// TestAllDecls checks that unexported declarations are only included when requested and that they
+// can be told apart from the exported ones.
+func TestAllDecls(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/priv\n\ngo 1.21\n",
+		"priv.go": `package priv
+
+import "errors"
+
+const (
+	Public  = 1
+	private = 2
+)
+
+var (
+	hidden    = "x"
+	errHidden = errors.New("hidden")
+)
+
+func helper() int { return 0 }
+
+// T is exported.
+type T struct {
+	Name   string
+	secret int
+}
+
+func newT() *T { return nil }
+
+// Do is exported.
+func (T) Do() {}
+
+func (t *T) undo() {}
+
+type inner interface {
+	Exported()
+	hidden()
+}
+`,
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := pkg.Config{Dir: root, Env: append(os.Environ(), "GOWORK=off")}
+	exported, err := config.Load(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AllDecls = true
+	all, err := config.Load(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		p         pkg.Package
+		constants []string
+		variables []string
+		errors    []string
+		functions []string
+		types     []string
+		fields    []string
+		methods   []string
+	}{
+		{exported, []string{"Public"}, nil, nil, nil, []string{"T"}, []string{"Name"}, []string{"Do"}},
+		{all, []string{"Public", "private"}, []string{"hidden", "errHidden"}, []string{"errHidden"}, []string{"helper"},
+			[]string{"T", "inner"}, []string{"Name", "secret"}, []string{"Do", "undo"}},
+	}
+	for i, test := range tests {
+		var constants, variables, errs, functions, types []string
+		exportedNames := make(map[string]bool)
+		for _, cb := range test.p.ConstantBlocks() {
+			for _, c := range cb.Constants() {
+				constants = append(constants, c.Name())
+				exportedNames[c.Name()] = c.Exported()
+			}
+		}
+		for _, vb := range test.p.VariableBlocks() {
+			for _, v := range vb.Variables() {
+				variables = append(variables, v.Name())
+				exportedNames[v.Name()] = v.Exported()
+			}
+			for _, e := range vb.Errors() {
+				errs = append(errs, e.Name())
+			}
+		}
+		for _, f := range test.p.Functions() {
+			functions = append(functions, f.Name())
+			exportedNames[f.Name()] = f.Exported()
+		}
+		for _, typ := range test.p.Types() {
+			types = append(types, typ.Name())
+			exportedNames[typ.Name()] = typ.Exported()
+		}
+
+		typ := test.p.Types()[0]
+		var fields, methods []string
+		for _, f := range typ.Fields() {
+			fields = append(fields, f.Name())
+		}
+		for _, m := range typ.Methods() {
+			methods = append(methods, m.Name())
+			exportedNames[m.Name()] = m.Exported()
+		}
+
+		for _, cmp := range []struct {
+			name string
+			want []string
+			have []string
+		}{
+			{"constants", test.constants, constants},
+			{"variables", test.variables, variables},
+			{"errors", test.errors, errs},
+			{"functions", test.functions, functions},
+			{"types", test.types, types},
+			{"fields", test.fields, fields},
+			{"methods", test.methods, methods},
+		} {
+			if err := cmpStringLists(cmp.want, cmp.have); err != nil {
+				t.Errorf("%d: %s: %s (have %v)", i, cmp.name, err.Error(), cmp.have)
+			}
+		}
+
+		for name, isExported := range exportedNames {
+			if want := ast.IsExported(name); isExported != want {
+				t.Errorf("%d: %s: incorrect exported flag (want %v, have %v)", i, name, want, isExported)
+			}
+		}
+	}
+
+	// Unexported constructors are grouped with their types, and unexported interface methods are kept.
+	if functions := all.Types()[0].Functions(); len(functions) != 1 || functions[0].Name() != "newT" || functions[0].Exported() {
+		t.Errorf("incorrect constructors: %v", functions)
+	}
+	if methods := all.Types()[1].InterfaceMethods(); len(methods) != 2 || methods[1].Exported() {
+		t.Errorf("incorrect interface methods: %v", methods)
+	}
+
+	// Unexported declarations are not part of the API.
+	if report := pkg.Diff(exported, all); !report.Empty() {
+		t.Errorf("unexpected API changes:\n%s", report)
+	}
+
+	// The other loaders should include unexported declarations too.
+	loaders := map[string]func() (pkg.Package, error){
+		"NewFromDir": func() (pkg.Package, error) { return config.NewFromDir(root) },
+		"NewFromSources": func() (pkg.Package, error) {
+			return config.NewFromSources(map[string][]byte{"priv.go": []byte(files["priv.go"])})
+		},
+		"Walk": func() (pkg.Package, error) {
+			var p pkg.Package
+			err := config.Walk(root, func(q pkg.Package) error {
+				p = q
+				return nil
+			})
+			return p, err
+		},
+	}
+	for name, load := range loaders {
+		p, err := load()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if functions := p.Functions(); len(functions) != 1 || functions[0].Name() != "helper" {
+			t.Errorf("%s: unexported function not included: %v", name, functions)
+		}
+	}
+
+	// Unexported methods of interfaces from other packages are included in method sets.
+	p, err := config.NewFromSources(map[string][]byte{
+		"wrap.go": []byte("package wrap\n\nimport \"reflect\"\n\ntype wrapped interface {\n\treflect.Type\n}\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, m := range p.Types()[0].InterfaceMethodSet() {
+		if m.Name() == "common" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("unexported method missing from method set")
+	}
+}
+
+// TestLookup checks that declarations can be looked up by name.
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	p, err := pkg.NewFromSources(map[string][]byte{"rpc.go": []byte(`package rpc
+
+import "errors"
+
+// Default port.
+const DefaultPort = 1234
+
+// ErrShutdown is returned after Close.
+var ErrShutdown = errors.New("connection is shut down")
+
+// Debug enables logging.
+var Debug = false
+
+// Dial connects to a server.
+func Dial(addr string) (*Client, error) { return nil, nil }
+
+// Client is a client.
+type Client struct {
+	// Addr is the server's address.
+	Addr string
+}
+
+// NewClient creates a client.
+func NewClient() *Client { return nil }
+
+// Call calls a method.
+func (c *Client) Call(method string) error { return nil }
+
+// Close closes the client.
+func (c *Client) Close() error { return nil }
+
+// Server is a server.
+type Server struct{}
+
+// Close closes the server.
+func (s *Server) Close() error { return nil }
+`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		opts  pkg.LookupOptions
+		want  []string
+		kinds []pkg.SymbolKind
+	}{
+		{"DefaultPort", pkg.LookupOptions{}, []string{"DefaultPort"}, []pkg.SymbolKind{pkg.KindConstant}},
+		{"ErrShutdown", pkg.LookupOptions{}, []string{"ErrShutdown"}, []pkg.SymbolKind{pkg.KindError}},
+		{"Debug", pkg.LookupOptions{}, []string{"Debug"}, []pkg.SymbolKind{pkg.KindVariable}},
+		{"Dial", pkg.LookupOptions{}, []string{"Dial"}, []pkg.SymbolKind{pkg.KindFunction}},
+		{"NewClient", pkg.LookupOptions{}, []string{"NewClient"}, []pkg.SymbolKind{pkg.KindFunction}},
+		{"Client", pkg.LookupOptions{}, []string{"Client"}, []pkg.SymbolKind{pkg.KindType}},
+		{"Client.Call", pkg.LookupOptions{}, []string{"Client.Call"}, []pkg.SymbolKind{pkg.KindMethod}},
+		{"Client.Addr", pkg.LookupOptions{}, []string{"Client.Addr"}, []pkg.SymbolKind{pkg.KindField}},
+		{"Close", pkg.LookupOptions{}, []string{"Client.Close", "Server.Close"}, []pkg.SymbolKind{pkg.KindMethod, pkg.KindMethod}},
+		{"client.call", pkg.LookupOptions{}, nil, nil},
+		{"client.call", pkg.LookupOptions{IgnoreCase: true}, []string{"Client.Call"}, []pkg.SymbolKind{pkg.KindMethod}},
+		{"Client.C", pkg.LookupOptions{Prefix: true}, []string{"Client.Call", "Client.Close"}, []pkg.SymbolKind{pkg.KindMethod, pkg.KindMethod}},
+		{"d", pkg.LookupOptions{IgnoreCase: true, Prefix: true}, []string{"DefaultPort", "Debug", "Dial"},
+			[]pkg.SymbolKind{pkg.KindConstant, pkg.KindVariable, pkg.KindFunction}},
+		{"Missing", pkg.LookupOptions{}, nil, nil},
+	}
+
+	for _, test := range tests {
+		var names []string
+		var kinds []pkg.SymbolKind
+		for _, s := range p.Symbols(test.query, test.opts) {
+			names = append(names, s.Name())
+			kinds = append(kinds, s.Kind())
+		}
+		if !reflect.DeepEqual(test.want, names) {
+			t.Errorf("%s: incorrect symbols (want %v, have %v)", test.query, test.want, names)
+		}
+		if !reflect.DeepEqual(test.kinds, kinds) {
+			t.Errorf("%s: incorrect kinds (want %v, have %v)", test.query, test.kinds, kinds)
+		}
+	}
+
+	// Each kind of symbol should have a one-line synopsis.
+	synopses := map[string]string{
+		"DefaultPort": "const DefaultPort untyped int",
+		"ErrShutdown": "var ErrShutdown error",
+		"Debug":       "var Debug bool",
+		"Dial":        "func Dial(addr string) (*Client, error)",
+		"Client":      "type Client struct",
+		"Client.Call": "func (c *Client) Call(method string) error",
+		"Client.Addr": "Addr string",
+	}
+	for name, want := range synopses {
+		if s, ok := p.Lookup(name); !ok || s.Synopsis() != want {
+			t.Errorf("%s: incorrect synopsis (want %q, have %q)", name, want, s.Synopsis())
+		}
+	}
+
+	// Each kind of symbol should only be available from its own accessor.
+	s, ok := p.Lookup("Client.Call")
+	if !ok {
+		t.Fatal("Client.Call not found")
+	}
+	if m, ok := s.Method(); !ok || m.Name() != "Call" {
+		t.Errorf("incorrect method for Client.Call: %v", m.Name())
+	}
+	if _, ok := s.Function(); ok {
+		t.Error("method is also a function")
+	}
+	if s.TypeName() != "Client" {
+		t.Errorf("incorrect type name (want Client, have %s)", s.TypeName())
+	}
+	if want, have := "Call calls a method.\n", s.Comments(0); want != have {
+		t.Errorf("incorrect comments (want %q, have %q)", want, have)
+	}
+
+	s, ok = p.Lookup("ErrShutdown")
+	if !ok {
+		t.Fatal("ErrShutdown not found")
+	}
+	if e, ok := s.Error(); !ok || e.Message() != "connection is shut down" {
+		t.Errorf("incorrect error for ErrShutdown: %v", e.Message())
+	}
+	if v, ok := s.Variable(); !ok || v.Name() != "ErrShutdown" {
+		t.Errorf("incorrect variable for ErrShutdown: %v", v.Name())
+	}
+
+	s, ok = p.Lookup("Client.Addr")
+	if !ok {
+		t.Fatal("Client.Addr not found")
+	}
+	if want, have := "Addr string", s.Source(); want != have {
+		t.Errorf("incorrect source for field (want %q, have %q)", want, have)
+	}
+
+	if _, ok := p.Lookup("Client.Missing"); ok {
+		t.Error("found missing method")
+	}
+}
+
+// TestIndex checks that declarations can be searched for across packages.
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	sources := []map[string][]byte{
+		{"ioutil.go": []byte(`package ioutil
+
+import "io"
+
+// ReadAll reads from r until an error or EOF and returns the data it read.
+func ReadAll(r io.Reader) ([]byte, error) { return nil, nil }
+
+// WriteFile writes data to the named file.
+func WriteFile(name string, data []byte) error { return nil }
+`)},
+		{"server.go": []byte(`package web
+
+// HTTPServer serves requests.
+type HTTPServer struct{}
+
+// ListenAndServe listens on the network address.
+func (s *HTTPServer) ListenAndServe(addr string) error { return nil }
+
+// ParseDuration parses a duration string.
+func ParseDuration(s string) (int, error) { return 0, nil }
+
+// Connection limits.
+const (
+	MaxConns = 10
+	MaxIdle  = 2
+)
+`)},
+	}
+	var pkgs []pkg.Package
+	for _, src := range sources {
+		p, err := pkg.NewFromSources(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs = append(pkgs, p)
+	}
+
+	idx := pkg.NewIndex(pkgs...)
+	if idx.Len() != 7 {
+		t.Errorf("incorrect number of declarations (want 7, have %v)", idx.Len())
+	}
+
+	tests := []struct {
+		query string
+		want  string
+		kind  pkg.SymbolKind
+	}{
+		{"ReadAll", "ReadAll", pkg.KindFunction},
+		{"io.Reader []byte", "ReadAll", pkg.KindFunction},
+		{"server", "HTTPServer", pkg.KindType},
+		{"http", "HTTPServer", pkg.KindType},
+		{"listen", "HTTPServer.ListenAndServe", pkg.KindMethod},
+		{"parse duration", "ParseDuration", pkg.KindFunction},
+		{"durration", "ParseDuration", pkg.KindFunction},
+		{"named file", "WriteFile", pkg.KindFunction},
+	}
+	for _, test := range tests {
+		results := idx.Search(test.query, 0)
+		if len(results) == 0 {
+			t.Errorf("%s: no results", test.query)
+			continue
+		}
+		if s := results[0].Symbol(); s.Name() != test.want || s.Kind() != test.kind {
+			t.Errorf("%s: incorrect best result (want %s %v, have %s %v)", test.query, test.kind, test.want, s.Kind(), s.Name())
+		}
+	}
+
+	// Results should be sorted by score.
+	results := idx.Search("file", 0)
+	for i := 1; i < len(results); i++ {
+		if results[i].Score() > results[i-1].Score() {
+			t.Errorf("results not sorted by score: %v > %v", results[i].Score(), results[i-1].Score())
+		}
+	}
+	if len(idx.Search("file", 1)) != 1 {
+		t.Error("limit not applied")
+	}
+	if results := idx.Search("the", 0); len(results) != 0 {
+		t.Errorf("stop word matched %v results", len(results))
+	}
+
+	// Constants in the same block should not match each other's names.
+	if results := idx.Search("maxidle", 0); len(results) != 1 || results[0].Symbol().Name() != "MaxIdle" {
+		t.Errorf("incorrect results for constant in block: %v", results)
+	}
+}
+
+// TestSignatureSearch checks that functions and methods are found by their signatures.
+func TestSignatureSearch(t *testing.T) {
+	t.Parallel()
+
+	sources := []map[string][]byte{
+		{"io.go": []byte(`package io
+
+// Reader is the interface that wraps the basic Read method.
+type Reader interface {
+	Read(p []byte) (n int, err error)
+}
+
+// ReadAll reads from r until an error or EOF and returns the data it read.
+func ReadAll(r Reader) ([]byte, error) { return nil, nil }
+`)},
+		{"os.go": []byte(`package os
+
+// File is an open file.
+type File struct{}
+
+// Read reads up to len(b) bytes from the file.
+func (f *File) Read(b []byte) (int, error) { return 0, nil }
+
+// Name returns the name of the file.
+func (f *File) Name() string { return "" }
+
+// Open opens the named file for reading.
+func Open(name string) (*File, error) { return nil, nil }
+`)},
+		{"strings.go": []byte(`package strings
+
+// Join concatenates the elements of elems, placing sep between them.
+func Join(elems []string, sep string) string { return "" }
+
+// Repeat returns count copies of s.
+func Repeat(s string, count int) string { return "" }
+
+// Index returns the index of v in s, or -1.
+func Index[E comparable](s []E, v E) int { return -1 }
+`)},
+	}
+	var pkgs []pkg.Package
+	for _, src := range sources {
+		p, err := pkg.NewFromSources(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs = append(pkgs, p)
+	}
+	idx := pkg.NewIndex(pkgs...)
+
+	tests := []struct {
+		desc  string
+		query string
+		want  string
+	}{
+		{"exact", "func(io.Reader) ([]byte, error)", "ReadAll"},
+		{"without func", "(string) (*os.File, error)", "Open"},
+		{"unqualified", "func(string) (*File, error)", "Open"},
+		{"order", "func(string, []string) string", "Join"},
+		{"variadic", "func(string, ...string) string", "Join"},
+		{"interface", "func(*os.File) ([]byte, error)", "ReadAll"},
+		{"receiver", "func(*os.File) string", "File.Name"},
+		{"method", "func([]byte) (int, error)", "File.Read"},
+		{"type parameter", "func([]int, int) int", "Index"},
+	}
+	for _, test := range tests {
+		results, err := idx.SearchSignature(test.query, 0)
+		if err != nil {
+			t.Errorf("%s: %v", test.desc, err)
+			continue
+		}
+		if len(results) == 0 {
+			t.Errorf("%s: no results", test.desc)
+			continue
+		}
+		if name := results[0].Symbol().Name(); name != test.want {
+			t.Errorf("%s: incorrect best result (want %v, have %v)", test.desc, test.want, name)
+		}
+	}
+
+	// An exact match should rank higher than one that needs an interface.
+	results, err := idx.SearchSignature("func(*os.File) ([]byte, error)", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exact, err := idx.SearchSignature("func(io.Reader) ([]byte, error)", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || len(exact) == 0 || results[0].Score() >= exact[0].Score() {
+		t.Error("interface match not ranked below exact match")
+	}
+
+	// Types that do not satisfy the interface should not match.
+	results, err = idx.SearchSignature("func(string) ([]byte, error)", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("incorrect number of results for non-matching type (want 0, have %v)", len(results))
+	}
+
+	for _, query := range []string{"func(", "int"} {
+		if _, err := idx.SearchSignature(query, 0); err == nil {
+			t.Errorf("%s: invalid signature did not return an error", query)
+		}
+	}
+}
+
+func TestSignatureSearchPackageNames(t *testing.T) {
+	t.Parallel()
+
+	// Set up a module with two packages of the same name, only one of which has a type that satisfies
+	// io.Reader.
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.21\n",
+		"a/tmpl.go":  "package tmpl\n\n// Source is readable.\ntype Source struct{}\n\n// Read reads nothing.\nfunc (Source) Read(p []byte) (int, error) { return 0, nil }\n",
+		"b/tmpl.go":  "package tmpl\n\n// Source is not readable.\ntype Source struct{}\n",
+		"use/use.go": "package use\n\nimport \"io\"\n\n// Consume reads everything from r.\nfunc Consume(r io.Reader) error { return nil }\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := pkg.Config{Dir: root, Env: append(os.Environ(), "GOWORK=off")}
+	var pkgs []pkg.Package
+	for _, pattern := range []string{"io", "example.com/m/use", "example.com/m/a", "example.com/m/b"} {
+		p, err := config.Load(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs = append(pkgs, p)
+	}
+
+	// The result should not depend on which of the packages is indexed last.
+	reversed := []pkg.Package{pkgs[0], pkgs[1], pkgs[3], pkgs[2]}
+	for i, idx := range []pkg.Index{pkg.NewIndex(pkgs...), pkg.NewIndex(reversed...)} {
+		results, err := idx.SearchSignature("func(tmpl.Source) error", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, result := range results {
+			if result.ImportPath() == "example.com/m/use" && result.Symbol().Name() == "Consume" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("order %v: Consume not found", i)
+		}
+	}
+}
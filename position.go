@@ -0,0 +1,85 @@
+// This file contains the information and logic for the Position type.
+package pkg
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+)
+
+// Position describes where a declaration is located in a package's source files.
+type Position struct {
+	// Name of the file, relative to the package's directory.
+	file string
+
+	// Line (starting at 1) and column (starting at 1, in bytes) where the declaration starts.
+	line   int
+	column int
+
+	// Line and column immediately after the end of the declaration.
+	endLine   int
+	endColumn int
+}
+
+// newPosition builds a new Position object for the source range from pos to end.
+func newPosition(pos, end token.Pos, fset *token.FileSet) Position {
+	if fset == nil || !pos.IsValid() {
+		return Position{}
+	}
+
+	start := fset.Position(pos)
+	p := Position{
+		file:   filepath.Base(start.Filename),
+		line:   start.Line,
+		column: start.Column,
+	}
+
+	if end.IsValid() {
+		stop := fset.Position(end)
+		p.endLine = stop.Line
+		p.endColumn = stop.Column
+	}
+
+	return p
+}
+
+// File returns the name of the file that contains the declaration, relative to the package's
+// directory.
+func (p Position) File() string {
+	return p.file
+}
+
+// Line returns the line number where the declaration starts. Lines start at 1.
+func (p Position) Line() int {
+	return p.line
+}
+
+// Column returns the column where the declaration starts, in bytes. Columns start at 1.
+func (p Position) Column() int {
+	return p.column
+}
+
+// EndLine returns the line number where the declaration ends.
+func (p Position) EndLine() int {
+	return p.endLine
+}
+
+// EndColumn returns the column immediately after the end of the declaration, in bytes.
+func (p Position) EndColumn() int {
+	return p.endColumn
+}
+
+// IsValid reports whether or not the position is known.
+func (p Position) IsValid() bool {
+	return p.line > 0
+}
+
+// String returns the string representation of this position, like "file.go:10:2", or "-" if the
+// position is not known.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.file, p.line, p.column)
+}
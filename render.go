@@ -0,0 +1,164 @@
+// This file contains the logic that is shared by all of the documentation renderers.
+package pkg
+
+import (
+	"go/doc/comment"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Section is a set of one or more sections of a package's documentation.
+type Section int
+
+const (
+	// SectionOverview is the package's heading, import path, and overview comments.
+	SectionOverview Section = 1 << iota
+
+	// SectionIndex is the list of links to every constant, variable, function, type, and method.
+	SectionIndex
+
+	// SectionConstants is the list of blocks of constants.
+	SectionConstants
+
+	// SectionVariables is the list of blocks of variables.
+	SectionVariables
+
+	// SectionFunctions is the list of functions that are not grouped under a type.
+	SectionFunctions
+
+	// SectionTypes is the list of types, each with its constructors and methods.
+	SectionTypes
+
+	// SectionAll is every section.
+	SectionAll = SectionOverview | SectionIndex | SectionConstants | SectionVariables | SectionFunctions | SectionTypes
+)
+
+// Has reports whether or not every section in sections is in s.
+func (s Section) Has(sections Section) bool {
+	return s&sections == sections
+}
+
+// majorVersion matches the major version suffix at the end of an import path, like "/v2".
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// defaultPackageURL returns the URL of the documentation for the package at importPath on pkg.go.dev.
+func defaultPackageURL(importPath string) string {
+	return "https://pkg.go.dev/" + importPath
+}
+
+// hasSymbol reports whether or not name (or recv.name, for methods) is declared in the package.
+func (p Package) hasSymbol(recv string, name string) bool {
+	if recv == "" {
+		for _, f := range p.functions {
+			if f.name == name {
+				return true
+			}
+		}
+		for _, cb := range p.constantBlocks {
+			for _, c := range cb.constants {
+				if c.name == name {
+					return true
+				}
+			}
+		}
+		for _, vb := range p.variableBlocks {
+			for _, v := range vb.variables {
+				if v.name == name {
+					return true
+				}
+			}
+		}
+	}
+
+	for _, t := range p.types {
+		switch {
+		case recv == "" && t.name == name:
+			return true
+		case recv == "":
+			for _, f := range t.functions {
+				if f.name == name {
+					return true
+				}
+			}
+		case recv == t.name:
+			for _, m := range t.methods {
+				if m.name == name {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// importNames maps the name of every package imported by the package to its import path. A package's
+// name is assumed to be the last element of its import path without any major version suffix, like
+// "yaml" for "gopkg.in/yaml.v3" and "mux" for "github.com/gorilla/mux/v2".
+func (p Package) importNames() map[string]string {
+	names := make(map[string]string, len(p.imports))
+	for _, importPath := range p.imports {
+		names[importName(importPath)] = importPath
+	}
+
+	return names
+}
+
+// importName returns the assumed name of the package at importPath.
+func importName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersion.MatchString(name) {
+		name = path.Base(path.Dir(importPath))
+	}
+	if i := strings.Index(name, ".v"); i > 0 && majorVersion.MatchString(name[i+1:]) {
+		name = name[:i]
+	}
+
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// newCommentParser returns a parser for the package's documentation that recognizes links to the
+// package's own declarations and to the packages that it imports.
+func newCommentParser(p Package) *comment.Parser {
+	names := p.importNames()
+
+	return &comment.Parser{
+		LookupSym: p.hasSymbol,
+		LookupPackage: func(name string) (string, bool) {
+			if importPath, ok := names[name]; ok {
+				return importPath, true
+			}
+
+			// Fall back to go/doc's default handling of standard library packages.
+			return "", false
+		},
+	}
+}
+
+// docLinkURL returns the URL for a link in documentation. Links to declarations in the same package
+// point to the declaration's anchor, and links to other packages use packageURL.
+func docLinkURL(link *comment.DocLink, packageURL func(string) string) string {
+	anchor := link.Name
+	if link.Recv != "" {
+		anchor = link.Recv + "." + link.Name
+	}
+
+	switch {
+	case link.ImportPath == "":
+		return "#" + anchor
+	case anchor == "":
+		return packageURL(link.ImportPath)
+	default:
+		return packageURL(link.ImportPath) + "#" + anchor
+	}
+}
+
+// oneLine collapses source that spans multiple lines into a single line.
+func oneLine(source string) string {
+	s := strings.Join(strings.Fields(source), " ")
+	s = strings.ReplaceAll(s, "( ", "(")
+	s = strings.ReplaceAll(s, ", )", ")")
+
+	return s
+}
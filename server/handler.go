@@ -0,0 +1,215 @@
+// This file contains the HTTP handlers for the server's pages and JSON API.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/doc"
+	"html"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/snhilde/pkg"
+)
+
+// defaultSearchLimit is the number of search results returned if the request does not specify a
+// limit.
+const defaultSearchLimit = 100
+
+// jsonSymbol is the JSON representation of a symbol returned by the API.
+type jsonSymbol struct {
+	ImportPath string `json:"importPath"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Symbol     any    `json:"symbol"`
+}
+
+// jsonResult is the JSON representation of a Result.
+type jsonResult struct {
+	ImportPath string `json:"importPath"`
+	Name       string `json:"name,omitempty"`
+	Kind       string `json:"kind"`
+	Synopsis   string `json:"synopsis,omitempty"`
+}
+
+// jsonError is the JSON representation of an error returned by the API.
+type jsonError struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP serves the server's pages and JSON API:
+//
+//	/                                   an HTML list of every package being served
+//	/pkg/{import path}                  the HTML documentation for a package
+//	/api/pkg/{import path}              a package as JSON, in the layout of pkg's schema
+//	/api/symbol/{import path}/{name}    a symbol (like "Open" or "File.Close") as JSON
+//	/api/search?q={query}&limit={n}     the results of Search as JSON
+//
+// Only GET and HEAD requests are allowed.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p := r.URL.Path
+	switch {
+	case p == "/":
+		s.serveIndex(w)
+	case strings.HasPrefix(p, "/pkg/"):
+		s.servePackageHTML(w, strings.Trim(strings.TrimPrefix(p, "/pkg/"), "/"))
+	case strings.HasPrefix(p, "/api/pkg/"):
+		s.servePackageJSON(w, strings.Trim(strings.TrimPrefix(p, "/api/pkg/"), "/"))
+	case strings.HasPrefix(p, "/api/symbol/"):
+		s.serveSymbolJSON(w, strings.Trim(strings.TrimPrefix(p, "/api/symbol/"), "/"))
+	case p == "/api/search":
+		s.serveSearchJSON(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveIndex serves the list of every package being served.
+func (s *Server) serveIndex(w http.ResponseWriter) {
+	sb := new(strings.Builder)
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Packages</title>\n</head>\n<body>\n")
+	sb.WriteString("<h1>Packages</h1>\n<ul>\n")
+	for _, p := range s.Packages() {
+		k := key(p)
+		fmt.Fprintf(sb, "<li><a href=\"%s\">%s</a>", html.EscapeString(packageURL(k)), html.EscapeString(k))
+		if synopsis := new(doc.Package).Synopsis(p.Comments(0)); synopsis != "" {
+			fmt.Fprintf(sb, " - %s", html.EscapeString(synopsis))
+		}
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</ul>\n</body>\n</html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, sb.String())
+}
+
+// servePackageHTML serves the HTML documentation for the package at importPath. Links to other
+// packages point to their pages on this server.
+func (s *Server) servePackageHTML(w http.ResponseWriter, importPath string) {
+	p, err := s.Package(importPath)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	sb := new(strings.Builder)
+	if err := pkg.RenderHTML(sb, p, pkg.HTMLOptions{PackageURL: packageURL}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, sb.String())
+}
+
+// servePackageJSON serves the package at importPath as JSON.
+func (s *Server) servePackageJSON(w http.ResponseWriter, importPath string) {
+	p, err := s.Package(importPath)
+	if err != nil {
+		writeJSONError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+// serveSymbolJSON serves a symbol as JSON. target is the symbol's import path and name joined by "/",
+// like "net/rpc/Client.Call".
+func (s *Server) serveSymbolJSON(w http.ResponseWriter, target string) {
+	importPath, name := path.Split(target)
+	importPath = strings.TrimSuffix(importPath, "/")
+	if importPath == "" || name == "" {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("missing import path or symbol in %q", target))
+		return
+	}
+
+	p, err := s.Package(importPath)
+	if err != nil {
+		writeJSONError(w, errorStatus(err), err)
+		return
+	}
+
+	sym, ok := p.Lookup(name)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no symbol %s in package %s", name, importPath))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jsonSymbol{
+		ImportPath: importPath,
+		Name:       sym.Name(),
+		Kind:       sym.Kind().String(),
+		Symbol:     sym.Value(),
+	})
+}
+
+// serveSearchJSON serves the results of a search as JSON. The query is in the "q" parameter and the
+// maximum number of results is in the optional "limit" parameter.
+func (s *Server) serveSearchJSON(w http.ResponseWriter, r *http.Request) {
+	limit := defaultSearchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", l))
+			return
+		}
+		limit = n
+	}
+
+	results := make([]jsonResult, 0)
+	for _, res := range s.Search(r.URL.Query().Get("q"), limit) {
+		results = append(results, jsonResult{
+			ImportPath: res.importPath,
+			Name:       res.name,
+			Kind:       res.kind,
+			Synopsis:   res.synopsis,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+// packageURL returns the URL of the page for the package at importPath on this server.
+func packageURL(importPath string) string {
+	return "/pkg/" + importPath
+}
+
+// errorStatus returns the HTTP status code for an error from loading a package.
+func errorStatus(err error) int {
+	if errors.Is(err, pkg.ErrNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+// writeJSON writes v as indented JSON with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// writeJSONError writes err as a JSON object with the given status code.
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	data, _ := json.Marshal(jsonError{Error: err.Error()})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
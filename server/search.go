@@ -0,0 +1,131 @@
+// This file contains the logic for searching the packages being served.
+package server
+
+import (
+	"go/doc"
+	"sort"
+	"strings"
+
+	"github.com/snhilde/pkg"
+)
+
+// Result holds a package or symbol that matched a search.
+type Result struct {
+	// Import path of the package that matched or that declares the symbol.
+	importPath string
+
+	// Symbol's name, like "Open" or "File.Close", or "" if the package itself matched.
+	name string
+
+	// Kind of declaration: "package", or the kind of symbol, like "function" or "method".
+	kind string
+
+	// One-line summary, like the first sentence of a package's documentation or a function's
+	// signature.
+	synopsis string
+}
+
+// Search returns the packages and symbols that match query. Packages whose import path or name
+// contains query (ignoring case) are listed first, sorted by import path. They are followed by the
+// symbols that match in the server's pkg.Index, ranked by how well they match. If query is a function
+// type, like "func(io.Reader) ([]byte, error)", only the functions and methods with a matching
+// signature are returned instead (see pkg.Index.SearchSignature). At most limit results are returned,
+// or all of them if limit is 0 or less.
+func (s *Server) Search(query string, limit int) []Result {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(query, "func(") {
+		// A query that cannot be parsed as a signature has no matches.
+		matches, _ := s.index().SearchSignature(query, limit)
+		return symbolResults(nil, matches)
+	}
+
+	var results []Result
+	lower := strings.ToLower(query)
+	for _, p := range s.Packages() {
+		if strings.Contains(strings.ToLower(p.ImportPath()), lower) || strings.Contains(strings.ToLower(p.Name()), lower) {
+			results = append(results, Result{
+				importPath: p.ImportPath(),
+				kind:       "package",
+				synopsis:   new(doc.Package).Synopsis(p.Comments(0)),
+			})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].importPath < results[j].importPath
+	})
+
+	results = symbolResults(results, s.index().Search(query, limit))
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// symbolResults appends the symbols in matches to results.
+func symbolResults(results []Result, matches []pkg.SearchResult) []Result {
+	for _, match := range matches {
+		sym := match.Symbol()
+		results = append(results, Result{
+			importPath: match.ImportPath(),
+			name:       sym.Name(),
+			kind:       sym.Kind().String(),
+			synopsis:   sym.Synopsis(),
+		})
+	}
+
+	return results
+}
+
+// index returns the index of every package being served, building it first if the packages have
+// changed since it was last built.
+func (s *Server) index() pkg.Index {
+	s.mu.RLock()
+	if s.idx != nil {
+		defer s.mu.RUnlock()
+		return *s.idx
+	}
+	s.mu.RUnlock()
+
+	// Build the index from a consistent set of packages, and only keep it if they have not changed
+	// in the meantime.
+	s.mu.Lock()
+	version := s.version
+	s.mu.Unlock()
+
+	idx := pkg.NewIndex(s.Packages()...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.version == version {
+		s.idx = &idx
+	}
+
+	return idx
+}
+
+// ImportPath returns the import path of the package that matched or that declares the symbol.
+func (r Result) ImportPath() string {
+	return r.importPath
+}
+
+// Name returns the symbol's name, like "Open" or "File.Close", or "" if the package itself matched.
+func (r Result) Name() string {
+	return r.name
+}
+
+// Kind returns the kind of declaration that matched: "package", or the kind of symbol, like
+// "function" or "method" (see pkg.SymbolKind).
+func (r Result) Kind() string {
+	return r.kind
+}
+
+// Synopsis returns a one-line summary of the result, like the first sentence of a package's
+// documentation or a function's signature.
+func (r Result) Synopsis() string {
+	return r.synopsis
+}
@@ -0,0 +1,339 @@
+// Package server serves the documentation for parsed packages over HTTP. Each package has an HTML page,
+// and the packages, their symbols, and a search across all of them are available as JSON. Packages are
+// reloaded when their source files change, so the server can be left running while editing code.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snhilde/pkg"
+)
+
+// Options holds the options for a Server.
+type Options struct {
+	// Config for loading packages that are requested by import path but were not added with Add. Those
+	// are loaded with Config's Load, which uses all of its fields. Packages that are added are found
+	// with Config's Walk, which uses only AllDecls. If nil, only added packages are served.
+	Config *pkg.Config
+
+	// Logger for errors that happen while reloading packages in the background. If nil, the log
+	// package's standard logger is used.
+	ErrorLog *log.Logger
+}
+
+// Server serves the documentation for a set of packages over HTTP. It is safe for concurrent use.
+type Server struct {
+	// Options the server was created with.
+	opts Options
+
+	// Guards packages, idx, and version.
+	mu sync.RWMutex
+
+	// Packages being served, keyed by import path.
+	packages map[string]*entry
+
+	// Search index of the packages being served, or nil if it needs to be built again.
+	idx *pkg.Index
+
+	// Number of times the packages being served have changed.
+	version int
+}
+
+// entry holds a package being served and what is needed to reload it.
+type entry struct {
+	// Most recently loaded version of the package.
+	pkg pkg.Package
+
+	// Function that loads the package again.
+	load func() (pkg.Package, error)
+
+	// Fingerprint of the package's source files when it was loaded.
+	stamp string
+}
+
+// New creates a new Server with no packages. Packages are added with Add, or are loaded on demand if
+// opts has a Config.
+func New(opts Options) *Server {
+	return &Server{
+		opts:     opts,
+		packages: make(map[string]*entry),
+	}
+}
+
+// Add loads every package matched by pattern and adds it to the server. pattern is the same as for
+// pkg.Walk, like "./..." or "net/...". Packages that can be loaded are added even if others cannot,
+// and the errors for the packages that could not be loaded are returned.
+func (s *Server) Add(pattern string) error {
+	var added []*entry
+	err := s.walk(pattern, func(p pkg.Package) error {
+		added = append(added, newEntry(p, s.dirLoader(p.Dir())))
+		return nil
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range added {
+		s.packages[key(e.pkg)] = e
+	}
+	s.changed()
+
+	return err
+}
+
+// newEntry creates an entry for p, which is loaded again with load.
+func newEntry(p pkg.Package, load func() (pkg.Package, error)) *entry {
+	return &entry{
+		pkg:   p,
+		load:  load,
+		stamp: fingerprint(p.Dir()),
+	}
+}
+
+// walk calls pkg.Walk, or the server's Config's Walk if it has one.
+func (s *Server) walk(pattern string, fn func(pkg.Package) error) error {
+	if s.opts.Config != nil {
+		return s.opts.Config.Walk(pattern, fn)
+	}
+
+	return pkg.Walk(pattern, fn)
+}
+
+// dirLoader returns a function that loads the package in dir the same way as Add does.
+func (s *Server) dirLoader(dir string) func() (pkg.Package, error) {
+	return func() (pkg.Package, error) {
+		var p pkg.Package
+		found := false
+		err := s.walk(dir, func(q pkg.Package) error {
+			p, found = q, true
+			return nil
+		})
+		if err != nil {
+			return pkg.Package{}, err
+		} else if !found {
+			return pkg.Package{}, fmt.Errorf("%w: %s", pkg.ErrNotFound, dir)
+		}
+
+		return p, nil
+	}
+}
+
+// key returns the key for p in the server's packages. Like pkg.Tree, a package without an import path
+// is keyed by its directory prefixed with "_".
+func key(p pkg.Package) string {
+	if p.ImportPath() != "" {
+		return p.ImportPath()
+	}
+
+	return "_" + filepath.ToSlash(p.Dir())
+}
+
+// Package returns the package with the given import path. If the package was not added and the
+// server has a Config, the package is loaded with it and kept for later requests. Only import paths
+// are loaded this way, not directories. The returned error wraps pkg.ErrNotFound if there is no such
+// package.
+func (s *Server) Package(importPath string) (pkg.Package, error) {
+	s.mu.RLock()
+	e, ok := s.packages[importPath]
+	s.mu.RUnlock()
+	if ok {
+		return e.pkg, nil
+	}
+
+	if s.opts.Config == nil || !isImportPath(importPath) {
+		return pkg.Package{}, fmt.Errorf("%w: %s", pkg.ErrNotFound, importPath)
+	}
+
+	config := *s.opts.Config
+	load := func() (pkg.Package, error) {
+		return config.Load(importPath)
+	}
+	p, err := load()
+	if err != nil {
+		return pkg.Package{}, err
+	} else if p.ImportPath() != importPath {
+		return pkg.Package{}, fmt.Errorf("%w: %s", pkg.ErrNotFound, importPath)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.packages[key(p)]; ok {
+		return e.pkg, nil
+	}
+	s.packages[key(p)] = newEntry(p, load)
+	s.changed()
+
+	return p, nil
+}
+
+// metaPatterns holds the names that the go command treats as patterns for many packages instead of as
+// import paths.
+var metaPatterns = map[string]bool{
+	"all":  true,
+	"cmd":  true,
+	"std":  true,
+	"tool": true,
+	"work": true,
+}
+
+// isImportPath reports whether or not importPath is a clean import path, like "net/http", and not a
+// directory, like "./foo", "../foo", or "/tmp/foo", or a pattern that the go command expands to many
+// packages, like "std" or "net/...".
+func isImportPath(importPath string) bool {
+	// Packages keyed by their directories begin with "_".
+	if importPath == "" || path.Clean(importPath) != importPath || strings.HasPrefix(importPath, "_") || strings.ContainsAny(importPath, `\:`) {
+		return false
+	}
+	if metaPatterns[importPath] || strings.Contains(importPath, "...") {
+		return false
+	}
+
+	for _, elem := range strings.Split(importPath, "/") {
+		if elem == "" || strings.HasPrefix(elem, ".") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Packages returns every package being served, sorted by import path.
+func (s *Server) Packages() []pkg.Package {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.packages))
+	for k := range s.packages {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pkgs := make([]pkg.Package, len(keys))
+	for i, k := range keys {
+		pkgs[i] = s.packages[k].pkg
+	}
+
+	return pkgs
+}
+
+// Reload checks the source files of every package being served and loads again the packages whose
+// files have changed. A package whose directory was removed is no longer served. If a package cannot
+// be loaded (like while a file is being edited), the previous version is kept and the error is
+// returned along with those for any other packages.
+func (s *Server) Reload() error {
+	s.mu.RLock()
+	stale := make(map[string]*entry)
+	for k, e := range s.packages {
+		if fingerprint(e.pkg.Dir()) != e.stamp {
+			stale[k] = e
+		}
+	}
+	s.mu.RUnlock()
+
+	var errs []error
+	for k, e := range stale {
+		if _, err := os.Stat(e.pkg.Dir()); errors.Is(err, os.ErrNotExist) {
+			s.remove(k, e)
+			continue
+		}
+
+		p, err := e.load()
+		if errors.Is(err, pkg.ErrNotFound) {
+			s.remove(k, e)
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("error reloading %s: %w", k, err))
+			continue
+		}
+
+		s.mu.Lock()
+		if s.packages[k] == e {
+			s.packages[k] = newEntry(p, e.load)
+			s.changed()
+		}
+		s.mu.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+// remove stops serving the package at key k if it is still e.
+func (s *Server) remove(k string, e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.packages[k] == e {
+		delete(s.packages, k)
+		s.changed()
+	}
+}
+
+// changed records that the packages being served have changed. s.mu must be held for writing.
+func (s *Server) changed() {
+	s.idx = nil
+	s.version++
+}
+
+// Watch calls Reload every interval until ctx is done, and then returns ctx's error. Errors from
+// Reload are written to the server's ErrorLog.
+func (s *Server) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := s.Reload(); err != nil {
+				s.logf("server: %v", err)
+			}
+		}
+	}
+}
+
+// logf writes a message to the server's ErrorLog.
+func (s *Server) logf(format string, args ...any) {
+	if s.opts.ErrorLog != nil {
+		s.opts.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// fingerprint returns a summary of the names, sizes, and modification times of the Go source files in
+// dir, which changes whenever one of the files is added, removed, or edited. A package without a
+// directory has no fingerprint.
+func fingerprint(dir string) string {
+	if dir == "" {
+		return ""
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	sb := new(strings.Builder)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(sb, "%s %d %d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,236 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/snhilde/pkg"
+	"github.com/snhilde/pkg/server"
+)
+
+// writeModule writes a module named example.com/shop with the given files and returns its directory.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/shop\n\ngo 1.21\n"
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// get requests target from s and returns the response's status code and body.
+func get(s *server.Server, target string) (int, string) {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+	return rec.Code, rec.Body.String()
+}
+
+// TestServer checks the server's pages and JSON API.
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	dir := writeModule(t, map[string]string{
+		"cart/cart.go": `// Package cart keeps track of what is being bought.
+package cart
+
+// Cart holds items.
+type Cart struct{}
+
+// NewCart creates an empty cart.
+func NewCart() *Cart { return &Cart{} }
+
+// AddItem adds an item to the cart.
+func (c *Cart) AddItem(name string) {}
+
+// MaxItems is the most items a cart can hold.
+const MaxItems = 10
+`,
+		"price/price.go": `// Package price formats prices.
+package price
+
+// Format formats cents as a price.
+func Format(cents int) string { return "" }
+`,
+	})
+
+	s := server.New(server.Options{})
+	if err := s.Add(dir + "/..."); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Packages()) != 2 {
+		t.Fatalf("incorrect number of packages (want 2, have %v)", len(s.Packages()))
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		code   int
+		want   string
+	}{
+		{"index", "/", http.StatusOK, `<a href="/pkg/example.com/shop/cart">example.com/shop/cart</a> - Package cart keeps track`},
+		{"html", "/pkg/example.com/shop/cart", http.StatusOK, "AddItem adds an item to the cart."},
+		{"package", "/api/pkg/example.com/shop/price", http.StatusOK, `"name": "Format"`},
+		{"method", "/api/symbol/example.com/shop/cart/Cart.AddItem", http.StatusOK, `"kind": "method"`},
+		{"constructor", "/api/symbol/example.com/shop/cart/NewCart", http.StatusOK, `"kind": "function"`},
+		{"constant", "/api/symbol/example.com/shop/cart/MaxItems", http.StatusOK, `"kind": "constant"`},
+		{"search", "/api/search?q=item", http.StatusOK, `"name": "MaxItems"`},
+		{"missing package", "/api/pkg/example.com/shop/missing", http.StatusNotFound, `"error"`},
+		{"missing symbol", "/api/symbol/example.com/shop/cart/Missing", http.StatusNotFound, `"error"`},
+		{"missing page", "/missing", http.StatusNotFound, ""},
+		{"invalid limit", "/api/search?q=item&limit=x", http.StatusBadRequest, `"error"`},
+	}
+
+	for _, test := range tests {
+		code, body := get(s, test.target)
+		if code != test.code {
+			t.Errorf("%s: incorrect status (want %v, have %v)", test.name, test.code, code)
+		}
+		if !strings.Contains(body, test.want) {
+			t.Errorf("%s: response missing %q:\n%s", test.name, test.want, body)
+		}
+	}
+
+	// Exact matches should be listed first.
+	results := s.Search("additem", 0)
+	if len(results) != 1 || results[0].Name() != "Cart.AddItem" || results[0].Kind() != "method" {
+		t.Errorf("incorrect search results for additem: %v", results)
+	}
+	results = s.Search("cart", 0)
+	if len(results) < 2 || results[0].Kind() != "package" || results[1].Name() != "Cart" {
+		t.Errorf("incorrect order of search results for cart: %v", results)
+	}
+	results = s.Search("func(int) string", 0)
+	if len(results) != 1 || results[0].Name() != "Format" {
+		t.Errorf("incorrect search results for signature: %v", results)
+	}
+}
+
+// TestServerReload checks that packages are loaded again when their source files change.
+func TestServerReload(t *testing.T) {
+	t.Parallel()
+
+	dir := writeModule(t, map[string]string{
+		"price.go": "package shop\n\nfunc Format(cents int) string { return \"\" }\n",
+	})
+
+	s := server.New(server.Options{})
+	if err := s.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	src := "package shop\n\nfunc Format(cents int) string { return \"\" }\n\nfunc Parse(s string) int { return 0 }\n"
+	if err := os.WriteFile(filepath.Join(dir, "price.go"), []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	code, body := get(s, "/api/pkg/example.com/shop")
+	if code != http.StatusOK {
+		t.Fatalf("incorrect status (want %v, have %v)", http.StatusOK, code)
+	}
+	var p pkg.Package
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Functions()) != 2 {
+		t.Errorf("incorrect number of functions after reload (want 2, have %v)", len(p.Functions()))
+	}
+
+	// A package that cannot be parsed keeps its previous version.
+	if err := os.WriteFile(filepath.Join(dir, "price.go"), []byte("package shop\n\nfunc {\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err == nil {
+		t.Error("no error for package that cannot be parsed")
+	}
+	if code, _ := get(s, "/api/pkg/example.com/shop"); code != http.StatusOK {
+		t.Errorf("incorrect status for previous version (want %v, have %v)", http.StatusOK, code)
+	}
+
+	// A package whose directory is removed is no longer served.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := get(s, "/api/pkg/example.com/shop"); code != http.StatusNotFound {
+		t.Errorf("incorrect status for removed package (want %v, have %v)", http.StatusNotFound, code)
+	}
+}
+
+// TestServerConfig checks that packages are loaded on demand with the server's Config, and that only
+// import paths are loaded this way.
+func TestServerConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := writeModule(t, map[string]string{
+		"shop.go":          "package shop\n\nfunc helper() {}\n",
+		"secret/secret.go": "package secret\n\nfunc Hidden() {}\n",
+	})
+
+	config := &pkg.Config{Dir: dir, Env: append(os.Environ(), "GOWORK=off"), AllDecls: true}
+	s := server.New(server.Options{Config: config})
+	if err := s.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Packages that were added should include unexported declarations.
+	if code, body := get(s, "/api/symbol/example.com/shop/helper"); code != http.StatusOK {
+		t.Errorf("incorrect status for unexported symbol (want %v, have %v): %s", http.StatusOK, code, body)
+	}
+
+	// Import paths should be loaded and kept under their import paths.
+	if code, body := get(s, "/api/pkg/example.com/shop/secret"); code != http.StatusOK {
+		t.Errorf("incorrect status for import path (want %v, have %v): %s", http.StatusOK, code, body)
+	}
+	if n := len(s.Packages()); n != 2 {
+		t.Errorf("incorrect number of packages (want 2, have %v)", n)
+	}
+
+	// Directories should not be loaded.
+	for _, target := range []string{
+		"/api/pkg/./secret",
+		"/api/pkg/../" + filepath.Base(dir) + "/secret",
+		"/api/pkg/example.com/shop/../shop/secret",
+		"/pkg/" + filepath.ToSlash(filepath.Join(dir, "secret")),
+	} {
+		if code, _ := get(s, target); code != http.StatusNotFound {
+			t.Errorf("%s: incorrect status (want %v, have %v)", target, http.StatusNotFound, code)
+		}
+	}
+	if n := len(s.Packages()); n != 2 {
+		t.Errorf("packages added for directories (want 2, have %v)", n)
+	}
+
+	// Patterns for many packages should not be loaded.
+	for _, target := range []string{
+		"/api/pkg/all",
+		"/api/pkg/std",
+		"/pkg/cmd",
+		"/api/pkg/example.com/shop/...",
+		"/api/pkg/example.com/shop...",
+		"/api/symbol/net/.../Dial",
+	} {
+		if code, _ := get(s, target); code != http.StatusNotFound {
+			t.Errorf("%s: incorrect status (want %v, have %v)", target, http.StatusNotFound, code)
+		}
+	}
+}
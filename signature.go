@@ -0,0 +1,548 @@
+// This file contains the logic for searching an Index by the signatures of functions and methods.
+package pkg
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Costs of the differences between a signature in a query and the signature of a function or method.
+// The lower the total cost, the closer the match.
+const (
+	// costQualifier is for a type in the query without a package name, like "Reader" for "io.Reader".
+	costQualifier = 0.5
+
+	// costVariadic is for a variadic parameter matched with a slice, like "...string" and "[]string".
+	costVariadic = 0.5
+
+	// costOrder is for inputs or outputs that match in a different order.
+	costOrder = 0.5
+
+	// costInterface is for a type that satisfies an interface in the other signature.
+	costInterface = 1
+
+	// costTypeParam is for a type parameter of a generic function, which matches any type.
+	costTypeParam = 1
+
+	// costReceiver is for a method's receiver that is not in the query.
+	costReceiver = 1
+
+	// costPointer is for a pointer matched with the type it points to, like "*os.File" and "os.File".
+	costPointer = 1.5
+
+	// costAny is for the empty interface, which every type satisfies.
+	costAny = 2
+
+	// costExtra is for each input or output of a function or method that is not in the query.
+	costExtra = 3
+)
+
+// maxExtra is the most inputs and outputs that a function or method can have that are not in the
+// query, not counting a method's receiver.
+const maxExtra = 2
+
+// maxParams is the most inputs or outputs that a function or method can have to be matched in every
+// order.
+const maxParams = 8
+
+// qualifier matches the package name that qualifies an identifier in a type, like "io." in
+// "map[string]io.Reader".
+var qualifier = regexp.MustCompile(`[\pL_][\pL\pN_]*\.`)
+
+// typeMethods holds the names of a type's methods, to decide whether or not it satisfies an
+// interface.
+type typeMethods struct {
+	// Whether or not the type is an interface.
+	isInterface bool
+
+	// Methods of the type, or every method in the method set if it is an interface.
+	value map[string]bool
+
+	// Methods of a pointer to the type, which also includes the methods with value receivers.
+	pointer map[string]bool
+}
+
+// funcSignature holds the types of the inputs and outputs of a function or method, each written in
+// the same canonical form.
+type funcSignature struct {
+	// Types of the inputs. For methods, the receiver is the first input.
+	inputs []string
+
+	// Types of the outputs.
+	outputs []string
+
+	// Whether or not the first input is a method's receiver.
+	receiver bool
+
+	// Names of the type parameters, which match any type.
+	typeParams map[string]bool
+
+	// Patterns that match the types which contain type parameters, keyed by type, like "^\[\].+$" for
+	// "[]T".
+	patterns map[string]*regexp.Regexp
+
+	// Import paths of the packages whose names qualify the types, keyed by package name. This is nil
+	// for a query, whose package names can stand for any package in the index with that name.
+	paths map[string]string
+}
+
+// newTypeMethods collects the names of the methods that t declares, or of every method in its method
+// set if t is an interface. Methods promoted from embedded fields are not included.
+func newTypeMethods(t Type) typeMethods {
+	tm := typeMethods{
+		isInterface: t.typeName == "interface",
+		value:       make(map[string]bool),
+		pointer:     make(map[string]bool),
+	}
+
+	if tm.isInterface {
+		for _, m := range t.methodSet {
+			tm.value[m.name] = true
+		}
+		for _, e := range t.embeddedInterfaces {
+			if e == "error" {
+				tm.value["Error"] = true
+			}
+		}
+		return tm
+	}
+
+	for _, m := range t.methods {
+		if !m.receiver.Pointer() {
+			tm.value[m.name] = true
+		}
+		tm.pointer[m.name] = true
+	}
+
+	return tm
+}
+
+// SearchSignature returns the functions and methods whose signatures best match query, with the
+// closest match first. query is a function type, like "func(io.Reader) ([]byte, error)", whose types
+// are qualified by their package names the same as in code outside of their packages. A package name
+// stands for every package in the index with that name, like "template" for both "text/template" and
+// "html/template". The "func" keyword can be left out.
+//
+// A function or method matches if every input and output in the query matches one of its own, in any
+// order, so "func(int, string)" also finds "func(string, int)". Types match if they are the same, or if
+// one satisfies an interface in the other: an input in the query matches an interface that the
+// function takes if it has all of the interface's methods (so "*os.File" finds functions that take an
+// io.Reader), and an output of the function matches an interface in the query in the same way. Only
+// the names of methods are compared, and only types in the index are known. A variadic parameter
+// matches a slice of the same type, type parameters match any type, and a method's receiver is an
+// input that can be left out of the query. Functions and methods can also have up to two inputs and
+// outputs that are not in the query. Each of these differences makes a match less close. At most limit
+// results are returned, or all of them if limit is 0 or less.
+func (idx Index) SearchSignature(query string, limit int) ([]SearchResult, error) {
+	want, err := parseSignature(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, e := range idx.entries {
+		if e.signature == nil {
+			continue
+		}
+		cost, ok := idx.matchSignature(want, *e.signature)
+		if !ok {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			importPath: e.importPath,
+			symbol:     e.symbol,
+			score:      1 / (1 + cost),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch {
+		case a.score != b.score:
+			return a.score > b.score
+		case a.importPath != b.importPath:
+			return a.importPath < b.importPath
+		default:
+			return a.symbol.name < b.symbol.name
+		}
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// parseSignature parses a function type from a query into a signature.
+func parseSignature(query string) (funcSignature, error) {
+	src := strings.TrimSpace(query)
+	if !strings.HasPrefix(src, "func") {
+		src = "func" + src
+	}
+
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return funcSignature{}, fmt.Errorf("invalid signature %q: %w", query, err)
+	}
+	ft, ok := expr.(*ast.FuncType)
+	if !ok {
+		return funcSignature{}, fmt.Errorf("invalid signature %q: not a function type", query)
+	}
+
+	var sig funcSignature
+	for _, list := range []struct {
+		fields *ast.FieldList
+		types  *[]string
+	}{{ft.Params, &sig.inputs}, {ft.Results, &sig.outputs}} {
+		if list.fields == nil {
+			continue
+		}
+		for _, field := range list.fields.List {
+			typ := canonicalType(field.Type, "", nil)
+			for i := 0; i < len(field.Names) || i == 0; i++ {
+				*list.types = append(*list.types, typ)
+			}
+		}
+	}
+
+	return sig, nil
+}
+
+// entrySignature returns the signature of a function or method in the index, with the types declared
+// in its package qualified by the package's name. paths holds the import paths of the package and of
+// the packages it imports, keyed by package name. This returns false for everything else.
+func entrySignature(e indexEntry, paths map[string]string) (funcSignature, bool) {
+	var receiver string
+	var typeParams []TypeParam
+	var inputs, outputs []Parameter
+	switch e.symbol.kind {
+	case KindFunction:
+		f := e.symbol.function
+		typeParams, inputs, outputs = f.typeParams, f.inputs, f.outputs
+	case KindMethod:
+		m := e.symbol.method
+		typeParams, inputs, outputs = m.typeParams, m.inputs, m.outputs
+		receiver = m.receiver.typeName
+		if receiver == "" {
+			// Methods of interfaces have no receiver of their own.
+			receiver = e.symbol.typeName
+		}
+	default:
+		return funcSignature{}, false
+	}
+
+	sig := funcSignature{
+		receiver:   receiver != "",
+		typeParams: make(map[string]bool, len(typeParams)),
+		paths:      paths,
+	}
+	for _, tp := range typeParams {
+		sig.typeParams[tp.name] = true
+	}
+
+	canonical := func(src string) string {
+		variadic := strings.HasPrefix(src, "...")
+		expr, err := parser.ParseExpr(strings.TrimPrefix(src, "..."))
+		if err != nil {
+			return src
+		}
+		if variadic {
+			return "..." + canonicalType(expr, e.pkgName, sig.typeParams)
+		}
+
+		return canonicalType(expr, e.pkgName, sig.typeParams)
+	}
+	if receiver != "" {
+		// Only the receiver's type matters, not its type parameters, like "*List" for "*List[T]".
+		name := strings.TrimPrefix(receiver, "*")
+		if i := strings.Index(name, "["); i >= 0 {
+			name = name[:i]
+		}
+		if strings.HasPrefix(receiver, "*") {
+			sig.inputs = append(sig.inputs, "*"+canonical(name))
+		} else {
+			sig.inputs = append(sig.inputs, canonical(name))
+		}
+	}
+	for _, p := range inputs {
+		sig.inputs = append(sig.inputs, canonical(p.typeName))
+	}
+	for _, p := range outputs {
+		sig.outputs = append(sig.outputs, canonical(p.typeName))
+	}
+	sig.patterns = typeParamPatterns(sig)
+
+	return sig, true
+}
+
+// typeParamPatterns builds the patterns for the inputs and outputs of sig that contain type parameters
+// (but are not just one type parameter), like "[]T" or "map[K]V". Each type parameter in a pattern
+// matches any type. Variadic parameters are written as slices, the same as when they are matched.
+func typeParamPatterns(sig funcSignature) map[string]*regexp.Regexp {
+	if len(sig.typeParams) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(sig.typeParams))
+	for name := range sig.typeParams {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	sort.Strings(names)
+	params := regexp.MustCompile(`\b(` + strings.Join(names, "|") + `)\b`)
+
+	patterns := make(map[string]*regexp.Regexp)
+	for _, typ := range append(append([]string{}, sig.inputs...), sig.outputs...) {
+		typ = strings.Replace(typ, "...", "[]", 1)
+		if _, ok := patterns[typ]; ok || sig.typeParams[typ] || !params.MatchString(typ) {
+			continue
+		}
+		pattern := params.ReplaceAllString(regexp.QuoteMeta(typ), `.+`)
+		if re, err := regexp.Compile(`^` + pattern + `$`); err == nil {
+			patterns[typ] = re
+		}
+	}
+
+	return patterns
+}
+
+// canonicalType writes a type in the form that signatures are compared in. If pkgName is not empty,
+// every identifier that is not predeclared or in typeParams is qualified with it, like "io.Reader" for
+// "Reader" in package io. The empty interface is written as "any".
+func canonicalType(expr ast.Expr, pkgName string, typeParams map[string]bool) string {
+	if pkgName != "" {
+		// Package names, selected names, and the names of parameters and methods are not types.
+		skip := make(map[*ast.Ident]bool)
+		ast.Inspect(expr, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if x, ok := n.X.(*ast.Ident); ok {
+					skip[x] = true
+				}
+				skip[n.Sel] = true
+			case *ast.Field:
+				for _, name := range n.Names {
+					skip[name] = true
+				}
+			case *ast.Ident:
+				if !skip[n] && !typeParams[n.Name] && types.Universe.Lookup(n.Name) == nil {
+					n.Name = pkgName + "." + n.Name
+				}
+			}
+			return true
+		})
+	}
+
+	return strings.ReplaceAll(types.ExprString(expr), "interface{}", "any")
+}
+
+// matchSignature returns the cost of the closest match between the signature in a query and the
+// signature of a function or method, or false if they do not match.
+func (idx Index) matchSignature(want funcSignature, have funcSignature) (float64, bool) {
+	inCost, inExtra, bound, ok := idx.matchTypes(want.inputs, have.inputs, true, have, nil)
+	if !ok {
+		return 0, false
+	}
+	outCost, outExtra, _, ok := idx.matchTypes(want.outputs, have.outputs, false, have, bound)
+	if !ok || inExtra+outExtra > maxExtra {
+		return 0, false
+	}
+
+	return inCost + outCost, true
+}
+
+// matchTypes finds the closest way to match every type in want with a different type in have, which
+// are the inputs (if input is true) or outputs of sig. Each type parameter in have can only stand for
+// one type, and those already in bound stand for the type they are bound to. It returns the cost of the
+// match, the number of types in have that were not matched (not counting a method's receiver), and the
+// type that each type parameter is bound to.
+func (idx Index) matchTypes(want []string, have []string, input bool, sig funcSignature, bound map[string]string) (float64, int, map[string]string, bool) {
+	if len(want) > len(have) || len(have) > maxParams {
+		return 0, 0, nil, false
+	}
+
+	// Work out the cost of every pair of types up front.
+	costs := make([][]float64, len(want))
+	matches := make([][]bool, len(want))
+	for i, w := range want {
+		costs[i] = make([]float64, len(have))
+		matches[i] = make([]bool, len(have))
+		for j, h := range have {
+			if b, ok := bound[h]; ok {
+				costs[i][j], matches[i][j] = idx.typeCost(w, b, input, funcSignature{paths: sig.paths})
+			} else {
+				costs[i][j], matches[i][j] = idx.typeCost(w, h, input, sig)
+			}
+		}
+	}
+
+	best, bestExtra, found := 0.0, 0, false
+	var bestBound map[string]string
+	used := make([]bool, len(have))
+	pairs := make([]int, len(want))
+	var assign func(i int, last int, reordered bool, cost float64)
+	assign = func(i int, last int, reordered bool, cost float64) {
+		if found && cost >= best {
+			return
+		}
+
+		if i < len(want) {
+			for j := range have {
+				if !used[j] && matches[i][j] {
+					used[j], pairs[i] = true, j
+					assign(i+1, j, reordered || j < last, cost+costs[i][j])
+					used[j] = false
+				}
+			}
+			return
+		}
+
+		// Each type parameter must stand for the same type everywhere it is matched.
+		bindings := make(map[string]string, len(bound))
+		for tp, typ := range bound {
+			bindings[tp] = typ
+		}
+		for i, j := range pairs {
+			tp := have[j]
+			if !sig.typeParams[tp] {
+				continue
+			}
+			if typ, ok := bindings[tp]; ok && typ != want[i] {
+				return
+			}
+			bindings[tp] = want[i]
+		}
+
+		// Everything in have that is left over makes the match less close.
+		extra := 0
+		if reordered {
+			cost += costOrder
+		}
+		for j := range have {
+			switch {
+			case used[j]:
+			case input && sig.receiver && j == 0:
+				cost += costReceiver
+			default:
+				cost += costExtra
+				extra++
+			}
+		}
+		if !found || cost < best {
+			best, bestExtra, bestBound, found = cost, extra, bindings, true
+		}
+	}
+	assign(0, -1, false, 0)
+
+	return best, bestExtra, bestBound, found
+}
+
+// typeCost returns the cost of matching a type in a query with a type in a function or method's
+// signature, or false if they do not match. If input is true, the types are inputs, and the query's
+// type can satisfy an interface in the signature. Otherwise, they are outputs, and the signature's type
+// can satisfy an interface in the query. sig is the signature that have is from.
+func (idx Index) typeCost(want string, have string, input bool, sig funcSignature) (float64, bool) {
+	if want == have {
+		return 0, true
+	}
+
+	// A variadic parameter is the same as a slice.
+	w, h := strings.Replace(want, "...", "[]", 1), strings.Replace(have, "...", "[]", 1)
+	if w == h {
+		return costVariadic, true
+	}
+
+	switch {
+	case sig.typeParams[h] || sig.patterns[h] != nil && sig.patterns[h].MatchString(w):
+		return costTypeParam, true
+	case !strings.Contains(w, ".") && qualifier.ReplaceAllString(h, "") == w:
+		return costQualifier, true
+	case "*"+w == h || w == "*"+h:
+		return costPointer, true
+	case input && h == "any", !input && w == "any":
+		return costAny, true
+	case input && idx.satisfies(w, nil, h, sig.paths), !input && idx.satisfies(h, sig.paths, w, nil):
+		return costInterface, true
+	}
+
+	return 0, false
+}
+
+// satisfies reports whether or not the type concrete has every method of the interface iface, for any
+// of the packages that their package names can stand for. concretePaths and ifacePaths resolve the
+// package names in each type, or are nil if the type is from a query (see typeKeys).
+func (idx Index) satisfies(concrete string, concretePaths map[string]string, iface string, ifacePaths map[string]string) bool {
+	for _, i := range idx.typeKeys(iface, ifacePaths) {
+		for _, c := range idx.typeKeys(concrete, concretePaths) {
+			if idx.implements(c, i) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// typeKeys returns the keys in the index's method sets that a type in a signature can refer to, like
+// "*os.File" for "*os.File" or "text/template.Template" for "template.Template". If paths has the
+// type's package name, the name stands for that package only. Otherwise, it stands for every package
+// in the index with that name. Types that are not qualified, like "error", are their own key.
+func (idx Index) typeKeys(typ string, paths map[string]string) []string {
+	ptr := ""
+	if strings.HasPrefix(typ, "*") {
+		ptr, typ = "*", typ[1:]
+	}
+
+	pkgName, typeName, ok := strings.Cut(typ, ".")
+	if !ok {
+		return []string{ptr + typ}
+	}
+	if importPath, ok := paths[pkgName]; ok {
+		return []string{ptr + importPath + "." + typeName}
+	}
+
+	keys := make([]string, 0, len(idx.pkgPaths[pkgName]))
+	for _, importPath := range idx.pkgPaths[pkgName] {
+		keys = append(keys, ptr+importPath+"."+typeName)
+	}
+
+	return keys
+}
+
+// implements reports whether or not the type named concrete has every method of the interface named
+// iface. Both names are keys in the index's method sets, like "*os.File" and "io.Reader". The empty
+// interface is not considered, and types that are not in the index implement nothing.
+func (idx Index) implements(concrete string, iface string) bool {
+	required := map[string]bool{"Error": true}
+	if iface != "error" {
+		tm, ok := idx.methods[iface]
+		if !ok || !tm.isInterface || len(tm.value) == 0 {
+			return false
+		}
+		required = tm.value
+	}
+
+	var have map[string]bool
+	switch {
+	case concrete == "error":
+		have = map[string]bool{"Error": true}
+	case strings.HasPrefix(concrete, "*"):
+		have = idx.methods[strings.TrimPrefix(concrete, "*")].pointer
+	default:
+		have = idx.methods[concrete].value
+	}
+
+	for name := range required {
+		if !have[name] {
+			return false
+		}
+	}
+
+	return true
+}
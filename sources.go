@@ -0,0 +1,130 @@
+// This file contains the logic for loading a package from in-memory source files.
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sourcesDir is the virtual directory that holds the in-memory source files.
+const sourcesDir = "."
+
+// sourceFile is an fs.FileInfo for an in-memory source file.
+type sourceFile struct {
+	name string
+	size int64
+}
+
+// sourcesContext returns a go/build Context that reads from the in-memory source files instead of
+// from the file system.
+func sourcesContext(sources map[string][]byte) *build.Context {
+	ctx := build.Default
+
+	ctx.IsDir = func(dir string) bool {
+		return dir == sourcesDir
+	}
+
+	ctx.HasSubdir = func(root, dir string) (string, bool) {
+		return "", false
+	}
+
+	ctx.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		if dir != sourcesDir {
+			return nil, fmt.Errorf("%s: %w", dir, fs.ErrNotExist)
+		}
+
+		infos := make([]fs.FileInfo, 0, len(sources))
+		for name, src := range sources {
+			infos = append(infos, sourceFile{name: name, size: int64(len(src))})
+		}
+		sort.Slice(infos, func(i, j int) bool {
+			return infos[i].Name() < infos[j].Name()
+		})
+
+		return infos, nil
+	}
+
+	ctx.OpenFile = func(file string) (io.ReadCloser, error) {
+		src, ok := sources[path.Base(file)]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", file, fs.ErrNotExist)
+		}
+
+		return io.NopCloser(bytes.NewReader(src)), nil
+	}
+
+	return &ctx
+}
+
+// parseSources parses all Go source files in sources, mirroring what go/parser's ParseDir does for
+// a directory on disk.
+func parseSources(fset *token.FileSet, sources map[string][]byte) (map[string]*ast.Package, error) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		if strings.HasSuffix(name, ".go") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	astPkgs := make(map[string]*ast.Package)
+	for _, name := range names {
+		astFile, err := parser.ParseFile(fset, name, sources[name], parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", name, err)
+		}
+
+		pkgName := astFile.Name.Name
+		astPkg, ok := astPkgs[pkgName]
+		if !ok {
+			astPkg = &ast.Package{
+				Name:  pkgName,
+				Files: make(map[string]*ast.File),
+			}
+			astPkgs[pkgName] = astPkg
+		}
+		astPkg.Files[name] = astFile
+	}
+
+	return astPkgs, nil
+}
+
+// Name returns the file's name.
+func (f sourceFile) Name() string {
+	return f.name
+}
+
+// Size returns the length in bytes of the file's contents.
+func (f sourceFile) Size() int64 {
+	return f.size
+}
+
+// Mode returns the file's mode bits. In-memory files are always regular, read-only files.
+func (f sourceFile) Mode() fs.FileMode {
+	return 0o444
+}
+
+// ModTime returns the file's modification time, which is always the zero time.
+func (f sourceFile) ModTime() time.Time {
+	return time.Time{}
+}
+
+// IsDir reports whether the file is a directory, which is always false.
+func (f sourceFile) IsDir() bool {
+	return false
+}
+
+// Sys returns the underlying data source, which is always nil.
+func (f sourceFile) Sys() interface{} {
+	return nil
+}
@@ -0,0 +1,102 @@
+// This file contains the information and logic for the TestFunction type.
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TestFunction holds information about a test, benchmark, or fuzz target in a package's test files.
+type TestFunction struct {
+	// Function's name, like "TestOpen" or "BenchmarkOpen".
+	name string
+
+	// Comments for this function.
+	comments string
+
+	// Location of this function's declaration.
+	position Position
+}
+
+// newTestFunctions builds the lists of tests, benchmarks, and fuzz targets declared in testFiles,
+// using the same rules as go test.
+func newTestFunctions(testFiles []*ast.File, fset *token.FileSet) ([]TestFunction, []TestFunction, []TestFunction) {
+	var tests, benchmarks, fuzzTargets []TestFunction
+	for _, f := range testFiles {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv != nil {
+				continue
+			}
+
+			tf := TestFunction{
+				name:     fd.Name.Name,
+				comments: fd.Doc.Text(),
+				position: newPosition(fd.Pos(), fd.End(), fset),
+			}
+			switch {
+			case isTestFunc(fd, "Test", "T"):
+				tests = append(tests, tf)
+			case isTestFunc(fd, "Benchmark", "B"):
+				benchmarks = append(benchmarks, tf)
+			case isTestFunc(fd, "Fuzz", "F"):
+				fuzzTargets = append(fuzzTargets, tf)
+			}
+		}
+	}
+
+	return tests, benchmarks, fuzzTargets
+}
+
+// isTestFunc reports whether or not fd is a function that go test runs, like "func TestX(t
+// *testing.T)". The function's name must begin with prefix, followed by anything that does not start
+// with a lowercase letter, and it must take a single pointer to the testing type named arg.
+func isTestFunc(fd *ast.FuncDecl, prefix string, arg string) bool {
+	name := fd.Name.Name
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(name[len(prefix):]); unicode.IsLower(r) {
+		return false
+	}
+
+	t := fd.Type
+	if t.TypeParams != nil || (t.Results != nil && len(t.Results.List) > 0) {
+		return false
+	}
+	if t.Params == nil || len(t.Params.List) != 1 || len(t.Params.List[0].Names) > 1 {
+		return false
+	}
+
+	// We can't know how the testing package was imported, so we only check for *T or *something.T.
+	ptr, ok := t.Params.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	switch x := ptr.X.(type) {
+	case *ast.Ident:
+		return x.Name == arg
+	case *ast.SelectorExpr:
+		return x.Sel.Name == arg
+	}
+
+	return false
+}
+
+// Name returns the function's name, like "TestOpen".
+func (tf TestFunction) Name() string {
+	return tf.name
+}
+
+// Comments returns the documentation for this function with pkg's formatting applied.
+func (tf TestFunction) Comments(width int) string {
+	return formatComments(tf.comments, width)
+}
+
+// Position returns the location of the function's declaration, including its body.
+func (tf TestFunction) Position() Position {
+	return tf.position
+}
@@ -0,0 +1,284 @@
+// This file contains the logic for rendering documentation as plain text in the layout of go doc.
+package pkg
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io"
+	"strings"
+)
+
+// TextOptions holds the options for rendering documentation as plain text.
+type TextOptions struct {
+	// Width at which comments are wrapped, including their indentation. If 0, comments are wrapped at
+	// 80 characters.
+	Width int
+
+	// Whether or not to render the full documentation for everything, like "go doc -all".
+	All bool
+
+	// Whether or not to render only a one-line summary of each item, like "go doc -short". This takes
+	// precedence over All.
+	Short bool
+}
+
+// textIndent is the indentation for comments below a declaration.
+const textIndent = "    "
+
+// textRenderer holds the state for rendering documentation as plain text.
+type textRenderer struct {
+	// Output being built.
+	sb strings.Builder
+
+	// Options for rendering.
+	opts TextOptions
+}
+
+// RenderText writes the documentation for p to w in the same layout as "go doc" does for a package.
+// By default, this is the package clause and overview followed by a one-line summary of every
+// constant, variable, function, and type, with each type's constructors listed below it. With
+// opts.All, the full documentation for everything is written in sections, and with opts.Short, only
+// the one-line summaries are written.
+func RenderText(w io.Writer, p Package, opts TextOptions) error {
+	r := newTextRenderer(opts)
+
+	if opts.Short {
+		r.packageSummary(p, false)
+		return r.write(w)
+	}
+
+	if p.importPath != "" {
+		fmt.Fprintf(&r.sb, "package %s // import %q\n\n", p.name, p.importPath)
+	} else {
+		fmt.Fprintf(&r.sb, "package %s\n\n", p.name)
+	}
+	if p.comments != "" {
+		r.sb.WriteString(formatComments(p.comments, r.opts.Width))
+		r.sb.WriteString("\n")
+	}
+
+	if !opts.All {
+		r.packageSummary(p, true)
+		return r.write(w)
+	}
+
+	if len(p.constantBlocks) > 0 {
+		r.sb.WriteString("CONSTANTS\n\n")
+		for _, cb := range p.constantBlocks {
+			r.declaration(cb.source, cb.comments)
+		}
+		r.sb.WriteString("\n")
+	}
+	if len(p.variableBlocks) > 0 {
+		r.sb.WriteString("VARIABLES\n\n")
+		for _, vb := range p.variableBlocks {
+			r.declaration(vb.source, vb.comments)
+		}
+		r.sb.WriteString("\n")
+	}
+	if len(p.functions) > 0 {
+		r.sb.WriteString("FUNCTIONS\n\n")
+		for _, f := range p.functions {
+			r.declaration(f.source, f.comments)
+		}
+		r.sb.WriteString("\n")
+	}
+	if len(p.types) > 0 {
+		r.sb.WriteString("TYPES\n\n")
+		for _, t := range p.types {
+			r.typeAll(t)
+		}
+	}
+
+	return r.write(w)
+}
+
+// RenderTypeText writes the documentation for t to w in the same layout as "go doc" does for a type,
+// but without the package clause. By default, this is the type's declaration and documentation
+// followed by the signatures of its constructors and methods. With opts.All, the full documentation
+// for each constructor and method is written as well, and with opts.Short, only a one-line summary of
+// the type is written.
+func RenderTypeText(w io.Writer, t Type, opts TextOptions) error {
+	r := newTextRenderer(opts)
+
+	switch {
+	case opts.Short:
+		r.sb.WriteString(typeSummary(t) + "\n")
+	case opts.All:
+		r.typeAll(t)
+	default:
+		r.declaration(t.source, t.comments)
+		for _, f := range t.functions {
+			r.sb.WriteString(oneLine(f.source) + "\n")
+		}
+		for _, m := range t.methods {
+			r.sb.WriteString(oneLine(m.source) + "\n")
+		}
+	}
+
+	return r.write(w)
+}
+
+// RenderFunctionText writes the documentation for f to w in the same layout as "go doc" does for a
+// function, but without the package clause. This is the function's signature followed by its
+// documentation, or only the signature with opts.Short.
+func RenderFunctionText(w io.Writer, f Function, opts TextOptions) error {
+	r := newTextRenderer(opts)
+
+	if opts.Short {
+		r.sb.WriteString(oneLine(f.source) + "\n")
+	} else {
+		r.declaration(f.source, f.comments)
+	}
+
+	return r.write(w)
+}
+
+// RenderMethodText writes the documentation for m to w in the same layout as "go doc" does for a
+// method, but without the package clause. This is the method's signature followed by its
+// documentation, or only the signature with opts.Short.
+func RenderMethodText(w io.Writer, m Method, opts TextOptions) error {
+	r := newTextRenderer(opts)
+
+	if opts.Short {
+		r.sb.WriteString(oneLine(m.source) + "\n")
+	} else {
+		r.declaration(m.source, m.comments)
+	}
+
+	return r.write(w)
+}
+
+// newTextRenderer creates a new renderer with the default options filled in.
+func newTextRenderer(opts TextOptions) *textRenderer {
+	if opts.Width <= 0 {
+		opts.Width = 80
+	}
+
+	return &textRenderer{opts: opts}
+}
+
+// write writes the rendered text to w.
+func (r *textRenderer) write(w io.Writer) error {
+	if _, err := io.WriteString(w, r.sb.String()); err != nil {
+		return fmt.Errorf("error writing text: %w", err)
+	}
+
+	return nil
+}
+
+// packageSummary renders a one-line summary of every constant block, variable block, function, and
+// type in the package. If constructors is true, each type's constructors are listed below it.
+func (r *textRenderer) packageSummary(p Package, constructors bool) {
+	for _, cb := range p.constantBlocks {
+		r.sb.WriteString(blockSummary(cb.source) + "\n")
+	}
+	for _, vb := range p.variableBlocks {
+		r.sb.WriteString(blockSummary(vb.source) + "\n")
+	}
+	for _, f := range p.functions {
+		r.sb.WriteString(oneLine(f.source) + "\n")
+	}
+	for _, t := range p.types {
+		r.sb.WriteString(typeSummary(t) + "\n")
+		if constructors {
+			for _, f := range t.functions {
+				r.sb.WriteString(textIndent + oneLine(f.source) + "\n")
+			}
+		}
+	}
+}
+
+// typeAll renders the full documentation for a type and all of its constructors and methods.
+func (r *textRenderer) typeAll(t Type) {
+	r.declaration(t.source, t.comments)
+	for _, f := range t.functions {
+		r.declaration(f.source, f.comments)
+	}
+	for _, m := range t.methods {
+		r.declaration(m.source, m.comments)
+	}
+}
+
+// declaration renders the source for a declaration followed by its indented documentation.
+func (r *textRenderer) declaration(source string, comments string) {
+	r.sb.WriteString(strings.TrimRight(source, "\n") + "\n")
+
+	if comments == "" {
+		r.sb.WriteString("\n")
+		return
+	}
+
+	text := formatComments(comments, r.opts.Width-len(textIndent))
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line != "" {
+			line = textIndent + line
+		}
+		r.sb.WriteString(line + "\n")
+	}
+	r.sb.WriteString("\n")
+}
+
+// blockSummary returns a one-line summary of a block of constants or variables, like
+// "const SeekStart = 0 ..." for a block that declares several constants.
+func blockSummary(source string) string {
+	lines := strings.Split(strings.TrimSpace(source), "\n")
+	if len(lines) == 1 {
+		return lines[0]
+	}
+
+	// Anything other than a group (like a single variable with a multi-line value) is summarized by
+	// its first line.
+	first := strings.TrimSpace(lines[0])
+	if !strings.HasSuffix(first, "(") {
+		return first + " ..."
+	}
+
+	// A group is summarized by its first declaration.
+	keyword := strings.TrimSpace(strings.TrimSuffix(first, "("))
+	for _, line := range lines[1:] {
+		line = stripComment(line)
+		if line == "" || line == ")" {
+			continue
+		}
+
+		return keyword + " " + oneLine(line) + " ..."
+	}
+
+	return first + " ... )"
+}
+
+// stripComment returns a line of source without any comment and surrounding space.
+func stripComment(line string) string {
+	src := []byte(line)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	for {
+		pos, tok, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.COMMENT {
+			line = line[:file.Offset(pos)]
+			break
+		}
+	}
+
+	return strings.TrimSpace(line)
+}
+
+// typeSummary returns a one-line summary of a type, like "type Reader interface{ ... }".
+func typeSummary(t Type) string {
+	s := "type " + t.name + typeParamList(t.typeParams)
+
+	switch t.typeName {
+	case "struct", "interface":
+		return s + " " + t.typeName + "{ ... }"
+	default:
+		return s + " " + oneLine(t.typeName)
+	}
+}
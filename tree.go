@@ -0,0 +1,342 @@
+// This file contains the logic for loading every package in a tree of directories.
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/build"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Tree holds a collection of packages that were loaded from a tree of directories.
+type Tree struct {
+	// Directory at the root of the tree.
+	root string
+
+	// Packages in the tree, keyed by import path.
+	packages map[string]Package
+
+	// Keys of the packages in the tree, sorted.
+	keys []string
+}
+
+// treeModule holds the module that contains a tree of directories.
+type treeModule struct {
+	// Module's path, like "github.com/snhilde/pkg".
+	path string
+
+	// Directory that contains the module's go.mod file.
+	root string
+}
+
+// NewTree loads every package in the directory root and all of its subdirectories, the same as
+// Walk(root + "/..."). root may be a directory or the import path of a package. The packages in the
+// tree are keyed by their import paths (see Walk for how they are determined). Like the go command,
+// a package without an import path is keyed by its directory prefixed with "_", like "_/tmp/foo".
+func NewTree(root string) (Tree, error) {
+	return newTree(root, 0)
+}
+
+// newTree does the work for NewTree. mode is passed to go/doc and controls which declarations are
+// included.
+func newTree(root string, mode doc.Mode) (Tree, error) {
+	dir, _, err := resolvePattern(root)
+	if err != nil {
+		return Tree{}, err
+	}
+
+	tree := Tree{
+		root:     dir,
+		packages: make(map[string]Package),
+	}
+	err = walk(strings.TrimSuffix(root, "/")+"/...", mode, func(dir string, p Package) error {
+		key := p.importPath
+		if key == "" {
+			key = "_" + filepath.ToSlash(dir)
+		}
+		tree.packages[key] = p
+		tree.keys = append(tree.keys, key)
+
+		return nil
+	})
+	sort.Strings(tree.keys)
+
+	return tree, err
+}
+
+// Walk loads every package matched by pattern and calls fn with each one in order of import path.
+// pattern is a directory or the import path of a package, and if it ends in "/..." (like "./..." or
+// "net/..."), every subdirectory is included as well. Like the go command, Walk skips directories
+// named "testdata" or "vendor", directories whose names begin with "_" or ".", directories that
+// contain another module, and directories without any Go files.
+//
+// The import path of each package is determined by the go.mod file of the module that contains it,
+// or by GOROOT and GOPATH if there is no module. Packages are loaded concurrently by a bounded pool of
+// workers. If fn returns an error, Walk stops and returns that error. Otherwise, Walk calls fn for
+// every package that could be loaded and returns the errors for any that could not.
+func Walk(pattern string, fn func(Package) error) error {
+	return walk(pattern, 0, func(_ string, p Package) error {
+		return fn(p)
+	})
+}
+
+// walk does the work for Walk, additionally passing fn the directory of each package. mode is passed
+// to go/doc and controls which declarations are included.
+func walk(pattern string, mode doc.Mode, fn func(string, Package) error) error {
+	dir, recursive, err := resolvePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	dirs := []string{dir}
+	if recursive {
+		dirs, err = walkDirs(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	mod, err := findModule(dir)
+	if err != nil {
+		return err
+	}
+
+	// Load all of the packages with a fixed number of workers.
+	type result struct {
+		dir string
+		pkg Package
+		err error
+	}
+	results := make([]result, len(dirs))
+	work := make(chan int)
+	wg := new(sync.WaitGroup)
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i].dir = dirs[i]
+				results[i].pkg, results[i].err = loadTreeDir(dirs[i], mod, mode)
+			}
+		}()
+	}
+	for i := range dirs {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	// Hand off the packages in order of import path.
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].pkg.importPath != results[j].pkg.importPath {
+			return results[i].pkg.importPath < results[j].pkg.importPath
+		}
+
+		return results[i].dir < results[j].dir
+	})
+
+	var errs []error
+	for _, r := range results {
+		var noGo *build.NoGoError
+		switch {
+		case errors.As(r.err, &noGo):
+			continue
+		case r.err != nil:
+			errs = append(errs, r.err)
+		default:
+			if err := fn(r.dir, r.pkg); err != nil {
+				return err
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolvePattern returns the absolute directory for pattern and whether or not the pattern includes
+// all subdirectories.
+func resolvePattern(pattern string) (string, bool, error) {
+	recursive := false
+	if pattern == "..." || strings.HasSuffix(pattern, "/...") {
+		recursive = true
+		pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+		if pattern == "" {
+			pattern = "."
+		}
+	}
+
+	// Patterns that are not directories are treated as import paths.
+	if info, err := os.Stat(pattern); err != nil || !info.IsDir() {
+		buildPkg, err := build.Import(pattern, ".", build.FindOnly)
+		if err != nil {
+			return "", false, fmt.Errorf("build error: invalid pattern %s: %w", pattern, err)
+		}
+		pattern = buildPkg.Dir
+	}
+
+	dir, err := filepath.Abs(pattern)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid directory %s: %w", pattern, err)
+	}
+
+	return dir, recursive, nil
+}
+
+// walkDirs returns root and every directory below it that might contain a package in the same module.
+func walkDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		if p != root {
+			name := d.Name()
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+
+			// Directories with their own go.mod file belong to a different module.
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		dirs = append(dirs, p)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking %s: %w", root, err)
+	}
+
+	return dirs, nil
+}
+
+// findModule finds the module that contains dir by looking for a go.mod file in dir and each of its
+// parents. If there is no go.mod file, this returns an empty module.
+func findModule(dir string) (treeModule, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		data, err := os.ReadFile(filepath.Join(d, "go.mod"))
+		if err == nil {
+			return treeModule{path: modulePath(data), root: d}, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return treeModule{}, fmt.Errorf("invalid module in %s: %w", d, err)
+		}
+
+		if filepath.Dir(d) == d {
+			return treeModule{}, nil
+		}
+	}
+}
+
+// modulePath returns the module path declared in the contents of a go.mod file.
+func modulePath(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+
+		if s, err := strconv.Unquote(fields[1]); err == nil {
+			return s
+		}
+
+		return fields[1]
+	}
+
+	return ""
+}
+
+// loadTreeDir loads the package in dir, which is part of mod. mode is passed to go/doc and controls
+// which declarations are included.
+func loadTreeDir(dir string, mod treeModule, mode doc.Mode) (Package, error) {
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return Package{}, fmt.Errorf("build error: invalid package in %s: %w", dir, err)
+	}
+
+	// The import path is the module's path joined with the directory's path inside the module. The
+	// standard library's module is special: its packages' import paths are not prefixed.
+	importPath := buildPkg.ImportPath
+	if mod.path != "" {
+		rel, err := filepath.Rel(mod.root, dir)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			if mod.path == "std" {
+				importPath = filepath.ToSlash(rel)
+			} else {
+				importPath = path.Join(mod.path, filepath.ToSlash(rel))
+			}
+		}
+	}
+	if importPath == "." {
+		importPath = ""
+	}
+
+	fset := token.NewFileSet()
+	astPkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
+	if err != nil {
+		return Package{}, fmt.Errorf("invalid package in %s: %w", dir, err)
+	}
+
+	pkg, err := parsePackage(buildPkg, astPkgs, fset, importPath, mode, nil)
+	if err != nil {
+		return Package{}, err
+	}
+
+	if mod.path != "" && mod.path != "std" {
+		pkg.modulePath = mod.path
+		pkg.moduleRoot = mod.root
+	}
+
+	return pkg, nil
+}
+
+// Root returns the directory at the root of the tree.
+func (t Tree) Root() string {
+	return t.root
+}
+
+// Len returns the number of packages in the tree.
+func (t Tree) Len() int {
+	return len(t.keys)
+}
+
+// ImportPaths returns the import paths of all packages in the tree, sorted.
+func (t Tree) ImportPaths() []string {
+	return append([]string{}, t.keys...)
+}
+
+// Packages returns all packages in the tree, sorted by import path.
+func (t Tree) Packages() []Package {
+	pkgs := make([]Package, len(t.keys))
+	for i, key := range t.keys {
+		pkgs[i] = t.packages[key]
+	}
+
+	return pkgs
+}
+
+// Package returns the package in the tree with the given import path.
+func (t Tree) Package(importPath string) (Package, bool) {
+	p, ok := t.packages[importPath]
+
+	return p, ok
+}
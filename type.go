@@ -5,6 +5,8 @@ import (
 	"go/ast"
 	"go/doc"
 	"go/token"
+	"go/types"
+	"sort"
 )
 
 // Type holds information about an exported type in a package.
@@ -18,18 +20,46 @@ type Type struct {
 	// Name of this type's underlying type.
 	typeName string
 
+	// Type parameters, if this is a generic type.
+	typeParams []TypeParam
+
 	// Original declaration in source for this type.
 	source string
 
+	// Fields for this type, if it is a struct.
+	fields []Field
+
+	// Methods declared directly in this type, if it is an interface.
+	interfaceMethods []Method
+
+	// Interfaces embedded in this type, if it is an interface.
+	embeddedInterfaces []string
+
+	// Full method set of this type with all embedded interfaces flattened, if it is an interface.
+	methodSet []Method
+
+	// Whether or not the method set has unexported methods, if it is an interface. This is known even
+	// if the unexported methods themselves were left out.
+	unexportedMethods bool
+
 	// Functions in the package that primarily return this type.
 	functions []Function
 
 	// Methods for this type.
 	methods []Method
+
+	// Location of this type's declaration.
+	position Position
+
+	// Build constraint that this type is declared under, or "" if it is built everywhere.
+	constraint string
+
+	// Examples of this type from the package's test files.
+	examples []Example
 }
 
 // newType builds a new Type object based on go/doc's Type.
-func newType(t *doc.Type, fset *token.FileSet) Type {
+func newType(t *doc.Type, fset *token.FileSet, ti *typeInfo) Type {
 	if t == nil {
 		return Type{}
 	}
@@ -40,35 +70,241 @@ func newType(t *doc.Type, fset *token.FileSet) Type {
 	// Extract the underlying type.
 	typeName := extractType(t, fset)
 
+	// Extract the type parameters.
+	var typeParams []TypeParam
+	if ts := typeSpec(t); ts != nil {
+		typeParams = newTypeParams(ts.TypeParams, fset, ti)
+	}
+
+	// Extract the fields if this is a struct, or the methods and embedded interfaces if this is an
+	// interface.
+	var fields []Field
+	var interfaceMethods []Method
+	var embeddedInterfaces []string
+	unexportedMethods := false
+	if ts := typeSpec(t); ts != nil {
+		switch tt := ts.Type.(type) {
+		case *ast.StructType:
+			fields = newFields(tt, fset)
+		case *ast.InterfaceType:
+			interfaceMethods, embeddedInterfaces = extractInterface(tt, t.Name, fset)
+			// go/doc marks interfaces whose unexported methods it removed as incomplete.
+			unexportedMethods = tt.Incomplete
+			for _, m := range interfaceMethods {
+				unexportedMethods = unexportedMethods || !m.Exported()
+			}
+		}
+	}
+
 	// Make a list of functions for this type.
 	functions := make([]Function, len(t.Funcs))
 	for i, f := range t.Funcs {
-		functions[i] = newFunction(f, fset)
+		functions[i] = newFunction(f, fset, ti)
 	}
 
 	// Make a list of methods for this type.
 	methods := make([]Method, len(t.Methods))
 	for i, m := range t.Methods {
-		methods[i] = newMethod(m, fset)
+		methods[i] = newMethod(m, fset, typeParams)
 	}
 
 	return Type{
-		name:      t.Name,
-		comments:  t.Doc,
-		typeName:  typeName,
-		source:    source,
-		functions: functions,
-		methods:   methods,
+		name:               t.Name,
+		comments:           t.Doc,
+		typeName:           typeName,
+		typeParams:         typeParams,
+		source:             source,
+		fields:             fields,
+		interfaceMethods:   interfaceMethods,
+		embeddedInterfaces: embeddedInterfaces,
+		unexportedMethods:  unexportedMethods,
+		functions:          functions,
+		methods:            methods,
+		position:           extractTypePosition(t, fset),
+	}
+}
+
+// extractTypePosition returns the location of the type's declaration. For a type declared on its own,
+// this begins at the "type" keyword. For a type declared in a group, this is only the type's
+// specification within the group.
+func extractTypePosition(t *doc.Type, fset *token.FileSet) Position {
+	ts := typeSpec(t)
+	if ts == nil {
+		return Position{}
+	}
+
+	if t.Decl.Lparen.IsValid() || !t.Decl.TokPos.IsValid() {
+		return newPosition(ts.Pos(), ts.End(), fset)
+	}
+
+	return newPosition(t.Decl.Pos(), ts.End(), fset)
+}
+
+// extractInterface extracts the methods declared directly in an interface type and the interfaces
+// embedded in it.
+func extractInterface(it *ast.InterfaceType, typeName string, fset *token.FileSet) ([]Method, []string) {
+	if it == nil || it.Methods == nil {
+		return nil, nil
+	}
+
+	methods := make([]Method, 0)
+	embedded := make([]string, 0)
+	for _, f := range it.Methods.List {
+		if len(f.Names) == 0 {
+			embedded = append(embedded, extractSource(f.Type, fset))
+		} else {
+			for _, name := range f.Names {
+				methods = append(methods, newInterfaceMethod(f, name.Name, typeName, fset))
+			}
+		}
+	}
+
+	return methods, embedded
+}
+
+// resolveMethodSets builds the full method set for every interface type in pkgTypes. Interfaces
+// embedded from the same package are flattened using their declarations, which keeps their
+// comments. If type information is available, methods from all other embedded interfaces (like those
+// from imported packages) are added as well. Unexported methods from type information are only added
+// if allDecls is true, but every interface with unexported methods is marked as having them.
+func resolveMethodSets(pkgTypes []Type, ti *typeInfo, allDecls bool) {
+	byName := make(map[string]int, len(pkgTypes))
+	for i, t := range pkgTypes {
+		byName[t.name] = i
+	}
+
+	for i := range pkgTypes {
+		if pkgTypes[i].typeName == "interface" {
+			pkgTypes[i].methodSet = methodSet(pkgTypes, i, byName, ti, allDecls, make(map[int]bool))
+			pkgTypes[i].unexportedMethods = hasUnexportedMethods(pkgTypes, i, byName, ti, make(map[int]bool))
+		}
+	}
+}
+
+// hasUnexportedMethods reports whether or not the method set of the interface type at index i in
+// pkgTypes has any unexported methods, either its own or from the interfaces that it embeds.
+func hasUnexportedMethods(pkgTypes []Type, i int, byName map[string]int, ti *typeInfo, visited map[int]bool) bool {
+	visited[i] = true
+	t := pkgTypes[i]
+	if t.unexportedMethods {
+		return true
+	}
+
+	if iface := lookupInterface(ti, t.name); iface != nil {
+		for k := 0; k < iface.NumMethods(); k++ {
+			if !iface.Method(k).Exported() {
+				return true
+			}
+		}
+	}
+
+	for _, e := range t.embeddedInterfaces {
+		j, ok := byName[e]
+		if ok && !visited[j] && pkgTypes[j].typeName == "interface" && hasUnexportedMethods(pkgTypes, j, byName, ti, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// methodSet returns the full method set for the interface type at index i in pkgTypes, sorted by name.
+func methodSet(pkgTypes []Type, i int, byName map[string]int, ti *typeInfo, allDecls bool, visited map[int]bool) []Method {
+	visited[i] = true
+	t := pkgTypes[i]
+
+	// Start with the methods declared directly in the interface and then add the methods from
+	// interfaces embedded from the same package.
+	set := make(map[string]Method)
+	for _, m := range t.interfaceMethods {
+		set[m.name] = m
 	}
+	for _, e := range t.embeddedInterfaces {
+		j, ok := byName[e]
+		if !ok || visited[j] || pkgTypes[j].typeName != "interface" {
+			continue
+		}
+		for _, m := range methodSet(pkgTypes, j, byName, ti, allDecls, visited) {
+			if _, ok := set[m.name]; !ok {
+				set[m.name] = m
+			}
+		}
+	}
+
+	// Fill in everything else from the type-checked interface.
+	if iface := lookupInterface(ti, t.name); iface != nil {
+		for k := 0; k < iface.NumMethods(); k++ {
+			f := iface.Method(k)
+			if _, ok := set[f.Name()]; !ok && (allDecls || f.Exported()) {
+				set[f.Name()] = newMethodFromFunc(f, ti)
+			}
+		}
+	}
+
+	methods := make([]Method, 0, len(set))
+	for _, m := range set {
+		methods = append(methods, m)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].name < methods[j].name
+	})
+
+	return methods
+}
+
+// isError reports whether or not this type implements the error interface, either through its own
+// Error() string method (on a value or pointer receiver) or through its interface method set.
+func (t Type) isError() bool {
+	for _, ms := range [][]Method{t.methods, t.methodSet} {
+		for _, m := range ms {
+			if m.name == "Error" && len(m.inputs) == 0 && len(m.outputs) == 1 && m.outputs[0].typeName == "string" {
+				return true
+			}
+		}
+	}
+
+	// Without type information, the method set does not include the methods of the built-in error
+	// interface.
+	for _, e := range t.embeddedInterfaces {
+		if e == "error" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupInterface returns the type-checked interface for the named type, or nil if type
+// information is not available or the type is not an interface.
+func lookupInterface(ti *typeInfo, name string) *types.Interface {
+	obj, ok := ti.lookup(name).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+
+	iface, _ := obj.Type().Underlying().(*types.Interface)
+
+	return iface
+}
+
+// typeSpec returns the type specification from go/doc's Type, or nil if there isn't one.
+func typeSpec(t *doc.Type) *ast.TypeSpec {
+	if t == nil || t.Decl == nil || len(t.Decl.Specs) == 0 {
+		return nil
+	}
+
+	ts, _ := t.Decl.Specs[0].(*ast.TypeSpec)
+
+	return ts
 }
 
 // extractType extracts the underlying type name for this type.
 func extractType(t *doc.Type, fset *token.FileSet) string {
-	if t == nil || t.Decl == nil || len(t.Decl.Specs) == 0 {
+	ts := typeSpec(t)
+	if ts == nil {
 		return ""
 	}
 
-	ts := t.Decl.Specs[0].(*ast.TypeSpec)
 	switch ts.Type.(type) {
 	case *ast.StructType:
 		return "struct"
@@ -84,6 +320,11 @@ func (t Type) Name() string {
 	return t.name
 }
 
+// Exported reports whether or not this type is exported.
+func (t Type) Exported() bool {
+	return ast.IsExported(t.name)
+}
+
 // Comments returns the documentation for this type with pkg's formatting applied.
 func (t Type) Comments(width int) string {
 	return formatComments(t.comments, width)
@@ -94,11 +335,57 @@ func (t Type) Type() string {
 	return t.typeName
 }
 
+// Position returns the location of the type's declaration.
+func (t Type) Position() Position {
+	return t.position
+}
+
+// Constraint returns the build constraint that the type is declared under, like "linux && amd64",
+// or "" if it is built everywhere. If the type is declared in more than one file (like once for
+// each operating system), the constraint is satisfied when any of those files is built.
+func (t Type) Constraint() string {
+	return t.constraint
+}
+
+// TypeParams returns a list of type parameters for this type if it is generic, or an empty list
+// otherwise.
+func (t Type) TypeParams() []TypeParam {
+	return append([]TypeParam{}, t.typeParams...)
+}
+
 // Source returns the source declaration for this type.
 func (t Type) Source() string {
 	return t.source
 }
 
+// Fields returns a list of fields for this type if it is a struct, or an empty list otherwise. The
+// list includes only the exported fields, unless the package was loaded with Config's AllDecls.
+func (t Type) Fields() []Field {
+	return append([]Field{}, t.fields...)
+}
+
+// InterfaceMethods returns a list of the methods declared directly in this type if it is an
+// interface, or an empty list otherwise. Methods from embedded interfaces are not included. To get
+// the full list of methods, see Type's InterfaceMethodSet.
+func (t Type) InterfaceMethods() []Method {
+	return append([]Method{}, t.interfaceMethods...)
+}
+
+// EmbeddedInterfaces returns a list of the interfaces embedded in this type if it is an interface, like
+// "io.Reader" or "Hash", or an empty list otherwise.
+func (t Type) EmbeddedInterfaces() []string {
+	return append([]string{}, t.embeddedInterfaces...)
+}
+
+// InterfaceMethodSet returns the full method set of this type if it is an interface, sorted by name,
+// or an empty list otherwise. Methods of interfaces embedded from the same package are always
+// included. Methods of interfaces embedded from other packages are included only if the package
+// could be type-checked. Those methods do not have comments, and their receiver is the interface that
+// declared them.
+func (t Type) InterfaceMethodSet() []Method {
+	return append([]Method{}, t.methodSet...)
+}
+
 // Functions returns a list of functions that primarily return this type.
 func (t Type) Functions() []Function {
 	return append([]Function{}, t.functions...)
@@ -108,3 +395,9 @@ func (t Type) Functions() []Function {
 func (t Type) Methods() []Method {
 	return append([]Method{}, t.methods...)
 }
+
+// Examples returns a list of examples of this type from the package's test files. This does not
+// include the examples of the type's functions and methods.
+func (t Type) Examples() []Example {
+	return append([]Example{}, t.examples...)
+}
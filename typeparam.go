@@ -0,0 +1,199 @@
+// This file contains the information and logic for the TypeParam and TypeTerm types.
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// TypeParam holds information about a type parameter of a generic type, function, or method.
+type TypeParam struct {
+	// Name of this type parameter.
+	name string
+
+	// Constraint for this type parameter as written in source, like "any" or "~int | ~string".
+	constraint string
+
+	// Type terms of this type parameter's constraint, if the constraint restricts the type set.
+	terms []TypeTerm
+}
+
+// TypeTerm holds information about a single term in the union of a type constraint, like "~int" in
+// "~int | ~string".
+type TypeTerm struct {
+	// Name of this term's type.
+	typeName string
+
+	// Whether or not this is an approximation (tilde) term.
+	tilde bool
+}
+
+// newTypeParams extracts all type parameters for the given list of fields.
+func newTypeParams(list *ast.FieldList, fset *token.FileSet, ti *typeInfo) []TypeParam {
+	if list == nil {
+		return nil
+	}
+
+	params := make([]TypeParam, 0)
+	for _, p := range list.List {
+		// Read out the constraint for this type parameter(s).
+		constraint := strings.TrimSpace(extractSource(p.Type, fset))
+		var terms []TypeTerm
+		if !isConstraintName(p.Type) {
+			terms = extractTerms(p.Type, fset)
+		}
+
+		for _, name := range p.Names {
+			param := TypeParam{
+				name:       name.Name,
+				constraint: constraint,
+				terms:      terms,
+			}
+
+			// Named constraints (like cmp.Ordered) can only be resolved with type information.
+			if len(param.terms) == 0 {
+				param.terms = resolveTerms(name, ti)
+			}
+
+			params = append(params, param)
+		}
+	}
+
+	return params
+}
+
+// extractTerms extracts the type terms that are written out in a constraint expression, like the
+// terms in "~int | ~string" or "interface{ ~[]byte | string }".
+func extractTerms(expr ast.Expr, fset *token.FileSet) []TypeTerm {
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		if e.Op != token.OR {
+			return nil
+		}
+		lhs := extractTerms(e.X, fset)
+		rhs := extractTerms(e.Y, fset)
+		if lhs == nil || rhs == nil {
+			return nil
+		}
+
+		return append(lhs, rhs...)
+	case *ast.UnaryExpr:
+		if e.Op != token.TILDE {
+			return nil
+		}
+
+		return []TypeTerm{{typeName: strings.TrimSpace(extractSource(e.X, fset)), tilde: true}}
+	case *ast.ParenExpr:
+		return extractTerms(e.X, fset)
+	case *ast.InterfaceType:
+		// Only an interface with a single union element (and no methods) has a simple list of terms.
+		if e.Methods == nil || len(e.Methods.List) != 1 || len(e.Methods.List[0].Names) != 0 {
+			return nil
+		}
+		if elem := e.Methods.List[0].Type; !isConstraintName(elem) {
+			return extractTerms(elem, fset)
+		}
+
+		return nil
+	default:
+		// A single type in a union, like the "int" in "int | string".
+		return []TypeTerm{{typeName: strings.TrimSpace(extractSource(expr, fset))}}
+	}
+}
+
+// resolveTerms uses type information to find the type terms for the type parameter declared by name.
+// This returns nil if there is no type information or the constraint does not restrict the type set.
+func resolveTerms(name *ast.Ident, ti *typeInfo) []TypeTerm {
+	if ti == nil {
+		return nil
+	}
+
+	obj, ok := ti.info.Defs[name].(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	tp, ok := obj.Type().(*types.TypeParam)
+	if !ok {
+		return nil
+	}
+	iface, ok := tp.Constraint().Underlying().(*types.Interface)
+	if !ok || iface.NumEmbeddeds() != 1 {
+		return nil
+	}
+
+	switch embedded := iface.EmbeddedType(0).(type) {
+	case *types.Union:
+		terms := make([]TypeTerm, embedded.Len())
+		for i := range terms {
+			term := embedded.Term(i)
+			terms[i] = TypeTerm{typeName: ti.typeString(term.Type()), tilde: term.Tilde()}
+		}
+
+		return terms
+	case *types.Interface:
+		return nil
+	default:
+		if _, ok := embedded.Underlying().(*types.Interface); ok {
+			return nil
+		}
+
+		return []TypeTerm{{typeName: ti.typeString(embedded)}}
+	}
+}
+
+// isConstraintName reports whether or not expr is a plain name, like "any" or "cmp.Ordered". On its
+// own, a name is more likely to refer to a constraint than to be a type term.
+func isConstraintName(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		return true
+	default:
+		return false
+	}
+}
+
+// Name returns the type parameter's name, like "T".
+func (tp TypeParam) Name() string {
+	return tp.name
+}
+
+// Constraint returns the type parameter's constraint as written in source, like "any", "cmp.Ordered",
+// or "~int | ~string".
+func (tp TypeParam) Constraint() string {
+	return tp.constraint
+}
+
+// Terms returns the list of type terms in the type parameter's constraint, like ~int and ~string
+// for the constraint "~int | ~string". Terms of named constraints (like cmp.Ordered) are included
+// only if the package could be type-checked. If the constraint does not restrict the type set to a
+// list of types (like "any" or "fmt.Stringer"), this returns an empty list.
+func (tp TypeParam) Terms() []TypeTerm {
+	return append([]TypeTerm{}, tp.terms...)
+}
+
+// String returns the string representation of this type parameter, like "T any".
+func (tp TypeParam) String() string {
+	return strings.TrimSpace(tp.name + " " + tp.constraint)
+}
+
+// Type returns the name of the term's type, like "int" in "~int".
+func (tt TypeTerm) Type() string {
+	return tt.typeName
+}
+
+// Tilde reports whether or not this is an approximation term, like "~int", which includes all types
+// whose underlying type is int.
+func (tt TypeTerm) Tilde() bool {
+	return tt.tilde
+}
+
+// String returns the string representation of this term, like "~int".
+func (tt TypeTerm) String() string {
+	if tt.tilde {
+		return "~" + tt.typeName
+	}
+
+	return tt.typeName
+}
@@ -2,16 +2,17 @@
 package pkg
 
 import (
+	"go/ast"
+	"go/constant"
 	"go/doc"
 	"go/token"
-	"regexp"
+	"go/types"
+	"strconv"
+	"strings"
 )
 
-var (
-	// These are used to find variable names that are exported errors.
-	errReName = regexp.MustCompilePOSIX(`^Err[[:upper:]][[:word:]]*$`)
-	errReFunc = regexp.MustCompilePOSIX(`^[[:upper:]][[:word:]]*[[:blank:]]*=[[:blank:]]*(errors.New|fmt.Errorf)`)
-)
+// errorInterface is the built-in error interface.
+var errorInterface = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
 
 // VariableBlock holds information about a block of one or more (grouped) exported variables in a
 // package.
@@ -30,22 +31,52 @@ type VariableBlock struct {
 
 	// List of errors within this block.
 	errors []Error
+
+	// Location of this block's declaration.
+	position Position
+
+	// Build constraint that this block is declared under, or "" if it is built everywhere.
+	constraint string
 }
 
 // Variable holds information about a single exported variable within a block.
 type Variable struct {
 	// Name of this variable.
 	name string
+
+	// Name of this variable's declared or inferred type.
+	typeName string
+
+	// Source of the expression that initializes this variable, if there is one.
+	initializer string
+
+	// Comments for this variable.
+	comments string
+
+	// Location of this variable's declaration.
+	position Position
+
+	// Build constraint that this variable is declared under, or "" if it is built everywhere.
+	constraint string
 }
 
 // Error holds information about a single exported error within a block.
 type Error struct {
 	// Name of this error.
 	name string
+
+	// Message of this error, if it is created from a constant string.
+	message string
+
+	// Location of this error's declaration.
+	position Position
+
+	// Build constraint that this error is declared under, or "" if it is built everywhere.
+	constraint string
 }
 
 // newVariableBlock builds a new VariableBlock object based on go/doc's Value.
-func newVariableBlock(v *doc.Value, t *doc.Type, fset *token.FileSet) VariableBlock {
+func newVariableBlock(v *doc.Value, t *doc.Type, fset *token.FileSet, ti *typeInfo) VariableBlock {
 	if v == nil {
 		return VariableBlock{}
 	}
@@ -59,15 +90,30 @@ func newVariableBlock(v *doc.Value, t *doc.Type, fset *token.FileSet) VariableBl
 	// Read out the source declaration (exported variables only).
 	source := extractSource(v.Decl, fset)
 
-	// Save the variable/error names.
-	variables := make([]Variable, len(v.Names))
+	// Save the variables/errors.
+	variables := make([]Variable, 0, len(v.Names))
 	errors := make([]Error, 0)
-	for i, n := range v.Names {
-		variables[i] = Variable{name: n}
+	for _, spec := range v.Decl.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		for i, name := range vs.Names {
+			// go/doc replaces unexported names that it can't remove with the blank identifier.
+			if name.Name == "_" {
+				continue
+			}
+			variable := newVariable(vs, i, name, fset, ti)
+			variables = append(variables, variable)
 
-		// If this is an error, add it to the list of errors in this block.
-		if errReName.MatchString(n) || errReFunc.MatchString(n) {
-			errors = append(errors, Error{name: n})
+			// If this is an error, add it to the list of errors in this block.
+			if isError(variable, name, ti) {
+				errors = append(errors, Error{
+					name:     name.Name,
+					message:  extractErrorMessage(vs, i, ti),
+					position: variable.position,
+				})
+			}
 		}
 	}
 
@@ -77,9 +123,114 @@ func newVariableBlock(v *doc.Value, t *doc.Type, fset *token.FileSet) VariableBl
 		source:    source,
 		variables: variables,
 		errors:    errors,
+		position:  newPosition(v.Decl.Pos(), v.Decl.End(), fset),
 	}
 }
 
+// newVariable builds a new Variable object for the variable declared by name, which is the ith name
+// in the value specification vs.
+func newVariable(vs *ast.ValueSpec, i int, name *ast.Ident, fset *token.FileSet, ti *typeInfo) Variable {
+	v := Variable{
+		name:     name.Name,
+		comments: specComments(vs),
+		position: newPosition(name.Pos(), vs.End(), fset),
+	}
+
+	// Read out the initializer. If a single expression initializes multiple variables (like a
+	// function with multiple results), that expression is the initializer for all of them.
+	switch {
+	case len(vs.Values) == len(vs.Names):
+		v.initializer = extractSource(vs.Values[i], fset)
+	case len(vs.Values) == 1:
+		v.initializer = extractSource(vs.Values[0], fset)
+	}
+
+	// Use the declared type if there is one. Otherwise, see if the type checker inferred it.
+	if vs.Type != nil {
+		v.typeName = strings.TrimSpace(extractSource(vs.Type, fset))
+	} else if ti != nil {
+		if obj, ok := ti.info.Defs[name].(*types.Var); ok {
+			v.typeName = ti.typeString(obj.Type())
+		}
+	}
+
+	return v
+}
+
+// isError reports whether or not the variable declared by name is an error. If type information is
+// available, this is any variable whose type implements the error interface. Otherwise, this is any
+// variable that is declared as an error or initialized by errors.New or fmt.Errorf.
+func isError(v Variable, name *ast.Ident, ti *typeInfo) bool {
+	if ti != nil {
+		if obj, ok := ti.info.Defs[name].(*types.Var); ok && obj.Type() != types.Typ[types.Invalid] {
+			return types.Implements(obj.Type(), errorInterface)
+		}
+	}
+
+	return v.typeName == "error" ||
+		strings.HasPrefix(v.initializer, "errors.New(") ||
+		strings.HasPrefix(v.initializer, "fmt.Errorf(")
+}
+
+// extractErrorMessage returns the message of the error that is the ith variable in vs, if the error
+// is created by errors.New or fmt.Errorf with a constant string. Otherwise, this returns "".
+func extractErrorMessage(vs *ast.ValueSpec, i int, ti *typeInfo) string {
+	if len(vs.Values) != len(vs.Names) {
+		return ""
+	}
+
+	call, ok := vs.Values[i].(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 || !isErrorConstructor(call.Fun, ti) {
+		return ""
+	}
+
+	// Prefer the type checker's value, which also resolves constants and concatenations.
+	arg := call.Args[0]
+	if ti != nil {
+		if tv, ok := ti.info.Types[arg]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+			return constant.StringVal(tv.Value)
+		}
+	}
+	if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// isErrorConstructor reports whether or not fun refers to errors.New or fmt.Errorf.
+func isErrorConstructor(fun ast.Expr, ti *typeInfo) bool {
+	// With type information, make sure that the function really comes from the errors or fmt package
+	// and not from a package that was imported under that name. This also catches calls from within
+	// the errors and fmt packages themselves.
+	if ti != nil {
+		ident, ok := fun.(*ast.Ident)
+		if sel, isSel := fun.(*ast.SelectorExpr); isSel {
+			ident, ok = sel.Sel, true
+		}
+		if ok {
+			if f, ok := ti.info.Uses[ident].(*types.Func); ok && f.Pkg() != nil {
+				path := f.Pkg().Path()
+
+				return (path == "errors" && f.Name() == "New") || (path == "fmt" && f.Name() == "Errorf")
+			}
+		}
+	}
+
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkgName, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	return (pkgName.Name == "errors" && sel.Sel.Name == "New") || (pkgName.Name == "fmt" && sel.Sel.Name == "Errorf")
+}
+
 // Type returns the name of the general, non-built-in type for this block of variables, or "" if this block
 // generally does not represent a non-built-in type.
 func (vb VariableBlock) Type() string {
@@ -96,13 +247,26 @@ func (vb VariableBlock) Source() string {
 	return vb.source
 }
 
+// Position returns the location of the declaration for this block of variables.
+func (vb VariableBlock) Position() Position {
+	return vb.position
+}
+
+// Constraint returns the build constraint that the block of variables is declared under, like
+// "linux && amd64", or "" if it is built everywhere.
+func (vb VariableBlock) Constraint() string {
+	return vb.constraint
+}
+
 // Variables returns a list of variables in this block of variables. The list includes variables of
 // type "error".
 func (vb VariableBlock) Variables() []Variable {
 	return append([]Variable{}, vb.variables...)
 }
 
-// Errors returns a list of only variables of type "error" in this block of variables.
+// Errors returns a list of only the variables in this block of variables whose type
+// implements the error interface. If the package could not be type-checked, this falls back to
+// variables that are declared as an error or initialized by errors.New or fmt.Errorf.
 func (vb VariableBlock) Errors() []Error {
 	return append([]Error{}, vb.errors...)
 }
@@ -112,7 +276,68 @@ func (v Variable) Name() string {
 	return v.name
 }
 
+// Exported reports whether or not this variable is exported.
+func (v Variable) Exported() bool {
+	return ast.IsExported(v.name)
+}
+
+// Type returns the name of the variable's type, like "error" or "*Logger". If the type is not
+// declared, this is the type inferred from the variable's initializer, or "" if the package could not
+// be type-checked.
+func (v Variable) Type() string {
+	return v.typeName
+}
+
+// Initializer returns the source of the expression that initializes the variable, like
+// `errors.New("EOF")`, or "" if the variable is not explicitly initialized.
+func (v Variable) Initializer() string {
+	return v.initializer
+}
+
+// Comments returns the documentation for this variable with pkg's formatting applied. This is either
+// the comment above the variable's specification or the comment on the same line. For the
+// documentation of the whole block, see VariableBlock's Comments.
+func (v Variable) Comments(width int) string {
+	return formatComments(v.comments, width)
+}
+
+// Position returns the location of the variable's declaration, from the variable's name to the end
+// of its specification.
+func (v Variable) Position() Position {
+	return v.position
+}
+
+// Constraint returns the build constraint that the variable is declared under, like
+// "linux && amd64", or "" if it is built everywhere.
+func (v Variable) Constraint() string {
+	return v.constraint
+}
+
 // Name returns the error's name.
 func (e Error) Name() string {
 	return e.name
 }
+
+// Exported reports whether or not this error is exported.
+func (e Error) Exported() bool {
+	return ast.IsExported(e.name)
+}
+
+// Position returns the location of the error's declaration, from the error's name to the end of its
+// specification.
+func (e Error) Position() Position {
+	return e.position
+}
+
+// Constraint returns the build constraint that the error is declared under, like "linux && amd64",
+// or "" if it is built everywhere.
+func (e Error) Constraint() string {
+	return e.constraint
+}
+
+// Message returns the error's message if the error is created by errors.New or fmt.Errorf with a
+// constant string, like "unexpected EOF". For fmt.Errorf, this is the unformatted format string.
+// Otherwise, this returns "".
+func (e Error) Message() string {
+	return e.message
+}